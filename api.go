@@ -95,13 +95,13 @@ type keyInstance struct {
 	keyMaterial []byte
 	userKey     []uint
 	KHat        [][]uint
+	subkeys     subkeys
 }
 
 func NewKeyInstance() *keyInstance {
 	ki := new(keyInstance)
 	ki.keyMaterial = make([]byte, MAX_KEY_SIZE)
 	ki.userKey = make([]uint, WORDS_PER_KEY)
-	ki.KHat = newKeySchedule()
 	return ki
 }
 
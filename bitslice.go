@@ -0,0 +1,124 @@
+/*
+	bitslice.go:  Serpent algorithm implementation in Go.
+
+	Fast backend. The data stays in bitslice form for all 32 rounds,
+	so the initial and final permutations of the reference code
+	are not needed and the subkeys are used without IP.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+// subkeys is the bitslice key schedule K (before IP), 4 words per round.
+type subkeys [WORDS_PER_KEY_SCHEDULE]uint32
+
+// sboxes are the bitsliced S-boxes S0..S7.
+var sboxes = [8]func(r0, r1, r2, r3 *uint32){sb0, sb1, sb2, sb3, sb4, sb5, sb6, sb7}
+
+// makeFastSubkeys computes the key schedule of a full-length userKey
+// with the bitsliced S-boxes, 32 bits at a time.
+// It gives the same subkeys as makeSubkeysBitslice.
+func makeFastSubkeys(userKey []uint, sk *subkeys) {
+	var w [8 + WORDS_PER_KEY_SCHEDULE]uint32
+	for i := 0; i < 8; i++ {
+		w[i] = uint32(userKey[i])
+	}
+	for i := 0; i < WORDS_PER_KEY_SCHEDULE; i++ {
+		x := w[i] ^ w[i+3] ^ w[i+5] ^ w[i+7] ^ uint32(phi) ^ uint32(i)
+		w[i+8] = (x << 11) | (x >> 21)
+	}
+	copy(sk[:], w[8:])
+	for i := 0; i < (r + 1); i++ {
+		k := sk[i*WORDS_PER_BLOCK : (i+1)*WORDS_PER_BLOCK]
+		sboxes[(r+3-i)%8](&k[0], &k[1], &k[2], &k[3])
+	}
+}
+
+func xorSubkey(x *[WORDS_PER_BLOCK]uint32, sk *subkeys, i int) {
+	x[0] ^= sk[i*WORDS_PER_BLOCK]
+	x[1] ^= sk[i*WORDS_PER_BLOCK+1]
+	x[2] ^= sk[i*WORDS_PER_BLOCK+2]
+	x[3] ^= sk[i*WORDS_PER_BLOCK+3]
+}
+
+// encryptBitslice encrypts the block x in place.
+// It gives the same result as encryptGivenKHat.
+func encryptBitslice(sk *subkeys, x *[WORDS_PER_BLOCK]uint32) {
+	for i := 0; i < r; i += 8 {
+		xorSubkey(x, sk, i)
+		sb0(&x[0], &x[1], &x[2], &x[3])
+		linear(x)
+		xorSubkey(x, sk, i+1)
+		sb1(&x[0], &x[1], &x[2], &x[3])
+		linear(x)
+		xorSubkey(x, sk, i+2)
+		sb2(&x[0], &x[1], &x[2], &x[3])
+		linear(x)
+		xorSubkey(x, sk, i+3)
+		sb3(&x[0], &x[1], &x[2], &x[3])
+		linear(x)
+		xorSubkey(x, sk, i+4)
+		sb4(&x[0], &x[1], &x[2], &x[3])
+		linear(x)
+		xorSubkey(x, sk, i+5)
+		sb5(&x[0], &x[1], &x[2], &x[3])
+		linear(x)
+		xorSubkey(x, sk, i+6)
+		sb6(&x[0], &x[1], &x[2], &x[3])
+		linear(x)
+		xorSubkey(x, sk, i+7)
+		sb7(&x[0], &x[1], &x[2], &x[3])
+		if i+7 < r-1 {
+			linear(x)
+		}
+	}
+	xorSubkey(x, sk, r)
+}
+
+// decryptBitslice decrypts the block x in place.
+// It gives the same result as decryptGivenKHat.
+func decryptBitslice(sk *subkeys, x *[WORDS_PER_BLOCK]uint32) {
+	xorSubkey(x, sk, r)
+	for i := r - 8; i >= 0; i -= 8 {
+		if i+7 < r-1 {
+			linearInv(x)
+		}
+		sb7Inv(&x[0], &x[1], &x[2], &x[3])
+		xorSubkey(x, sk, i+7)
+		linearInv(x)
+		sb6Inv(&x[0], &x[1], &x[2], &x[3])
+		xorSubkey(x, sk, i+6)
+		linearInv(x)
+		sb5Inv(&x[0], &x[1], &x[2], &x[3])
+		xorSubkey(x, sk, i+5)
+		linearInv(x)
+		sb4Inv(&x[0], &x[1], &x[2], &x[3])
+		xorSubkey(x, sk, i+4)
+		linearInv(x)
+		sb3Inv(&x[0], &x[1], &x[2], &x[3])
+		xorSubkey(x, sk, i+3)
+		linearInv(x)
+		sb2Inv(&x[0], &x[1], &x[2], &x[3])
+		xorSubkey(x, sk, i+2)
+		linearInv(x)
+		sb1Inv(&x[0], &x[1], &x[2], &x[3])
+		xorSubkey(x, sk, i+1)
+		linearInv(x)
+		sb0Inv(&x[0], &x[1], &x[2], &x[3])
+		xorSubkey(x, sk, i)
+	}
+}
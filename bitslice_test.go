@@ -0,0 +1,151 @@
+/*
+	bitslice_test.go:  Unit tests of the fast bitslice backend.
+	The reference implementation is used as the oracle.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+var sboxesInv = []func(r0, r1, r2, r3 *uint32){sb0Inv, sb1Inv, sb2Inv, sb3Inv, sb4Inv, sb5Inv, sb6Inv, sb7Inv}
+
+// Column j of the bitslice words holds nibble j, so all 16 inputs
+// of an S-box are checked with one call.
+func checkSBox(t *testing.T, name string, f func(r0, r1, r2, r3 *uint32), table []byte) {
+	var x [WORDS_PER_BLOCK]uint32
+	for j := uint(0); j < 16; j++ {
+		for b := uint(0); b < 4; b++ {
+			x[b] |= uint32((j>>b)&1) << j
+		}
+	}
+	f(&x[0], &x[1], &x[2], &x[3])
+	for j := uint(0); j < 16; j++ {
+		var n byte
+		for b := uint(0); b < 4; b++ {
+			n |= byte((x[b]>>j)&1) << b
+		}
+		if n != table[j] {
+			t.Errorf("%s(%d). Is %d, should: %d", name, j, n, table[j])
+		}
+	}
+}
+
+func TestSBoxBitslice(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		checkSBox(t, fmt.Sprintf("sb%d", i), sboxes[i], SBox[i])
+		checkSBox(t, fmt.Sprintf("sb%dInv", i), sboxesInv[i], SBoxInverse[i])
+	}
+}
+
+func randomBlock(rnd *rand.Rand) []uint {
+	x := NewBlockSlice()
+	for i := range x {
+		x[i] = uint(rnd.Uint32())
+	}
+	return x
+}
+
+func toArray(x []uint) [WORDS_PER_BLOCK]uint32 {
+	return [WORDS_PER_BLOCK]uint32{uint32(x[0]), uint32(x[1]), uint32(x[2]), uint32(x[3])}
+}
+
+// In bitslice form LT is FP(LT(IP(x))) of the reference code.
+func TestLinear(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for n := 0; n < 100; n++ {
+		x := randomBlock(rnd)
+		hat, lt, expected := NewBlockSlice(), NewBlockSlice(), NewBlockSlice()
+		IP(x, hat)
+		LT(hat, lt)
+		FP(lt, expected)
+
+		y := toArray(x)
+		linear(&y)
+		if y != toArray(expected) {
+			t.Fatalf("linear(%s). Is %x, should: %s", blockStr(x), y, blockStr(expected))
+		}
+		linearInv(&y)
+		if y != toArray(x) {
+			t.Fatalf("linearInv. Is %x, should: %s", y, blockStr(x))
+		}
+	}
+}
+
+func TestBitsliceMatchesReference(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	for n := 0; n < 50; n++ {
+		userKey := make([]uint, WORDS_PER_KEY)
+		for i := range userKey {
+			userKey[i] = uint(rnd.Uint32())
+		}
+		KHat := newKeySchedule()
+		makeSubkeys(userKey, KHat)
+		var sk subkeys
+		makeFastSubkeys(userKey, &sk)
+		K := newKeySchedule()
+		makeSubkeysBitslice(userKey, K)
+		for i := range K {
+			for j := range K[i] {
+				if sk[i*WORDS_PER_BLOCK+j] != uint32(K[i][j]) {
+					t.Fatalf("Subkey %d.%d. Is %08x, should: %08x", i, j, sk[i*WORDS_PER_BLOCK+j], K[i][j])
+				}
+			}
+		}
+
+		plainText := randomBlock(rnd)
+		expected := NewBlockSlice()
+		encryptGivenKHat(plainText, KHat, expected)
+
+		x := toArray(plainText)
+		encryptBitslice(&sk, &x)
+		if x != toArray(expected) {
+			t.Fatalf("encryptBitslice. Is %x, should: %s", x, blockStr(expected))
+		}
+
+		decrypted := NewBlockSlice()
+		decryptGivenKHat(expected, KHat, decrypted)
+		decryptBitslice(&sk, &x)
+		if x != toArray(decrypted) || x != toArray(plainText) {
+			t.Fatalf("decryptBitslice. Is %x, should: %s", x, blockStr(plainText))
+		}
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	block, _ := NewCipher(make([]byte, 32))
+	buf := make([]byte, BlockSize)
+	b.SetBytes(BlockSize)
+	for i := 0; i < b.N; i++ {
+		block.Encrypt(buf, buf)
+	}
+}
+
+func BenchmarkEncryptReference(b *testing.B) {
+	key := NewKeyInstance()
+	MakeKey(key, BITS_PER_KEY, []byte("0000000000000000000000000000000000000000000000000000000000000000"))
+	input, output := NewBlockSlice(), NewBlockSlice()
+	b.SetBytes(BlockSize)
+	for i := 0; i < b.N; i++ {
+		BlockEncrypt(key, input, output)
+	}
+}
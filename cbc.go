@@ -0,0 +1,128 @@
+/*
+	cbc.go:  Serpent algorithm implementation in Go.
+
+	CBC and ECB encryption of whole messages with padding.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"errors"
+
+	"github.com/piotrpsz/serpent/padding"
+)
+
+// EncryptCBC pads plaintext with the scheme p and encrypts it in CBC
+// mode under the 16-byte iv. The iv must be unpredictable.
+func EncryptCBC(b cipher.Block, iv, plaintext []byte, p padding.Scheme) ([]byte, error) {
+	if err := checkMessageBlock(b, iv); err != nil {
+		return nil, err
+	}
+	out := p.Pad(plaintext, BlockSize)
+	var x [BlockSize]byte
+	copy(x[:], iv)
+	cbcEncrypt(b, &x, out, out)
+	return out, nil
+}
+
+// DecryptCBC decrypts a message encrypted by EncryptCBC and removes
+// the padding. A bad padding gives padding.ErrPadding. The ciphertext
+// is not authenticated: combine CBC with a MAC before acting on errors.
+func DecryptCBC(b cipher.Block, iv, ciphertext []byte, p padding.Scheme) ([]byte, error) {
+	if err := checkMessageBlock(b, iv); err != nil {
+		return nil, err
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%BlockSize != 0 {
+		return nil, Error(BAD_LENGTH)
+	}
+	out := make([]byte, len(ciphertext))
+	var x [BlockSize]byte
+	copy(x[:], iv)
+	cbcDecrypt(b, &x, out, ciphertext)
+	return p.Unpad(out, BlockSize)
+}
+
+// EncryptECB pads plaintext with the scheme p and encrypts each block
+// on its own. Equal blocks give equal ciphertext; prefer an AEAD mode.
+func EncryptECB(b cipher.Block, plaintext []byte, p padding.Scheme) ([]byte, error) {
+	if err := checkMessageBlock(b, nil); err != nil {
+		return nil, err
+	}
+	out := p.Pad(plaintext, BlockSize)
+	encryptBlocksWith(b, out, out)
+	return out, nil
+}
+
+// DecryptECB decrypts a message encrypted by EncryptECB and removes
+// the padding.
+func DecryptECB(b cipher.Block, ciphertext []byte, p padding.Scheme) ([]byte, error) {
+	if err := checkMessageBlock(b, nil); err != nil {
+		return nil, err
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%BlockSize != 0 {
+		return nil, Error(BAD_LENGTH)
+	}
+	out := make([]byte, len(ciphertext))
+	decryptBlocksWith(b, out, ciphertext)
+	return p.Unpad(out, BlockSize)
+}
+
+func checkMessageBlock(b cipher.Block, iv []byte) error {
+	if b.BlockSize() != BlockSize {
+		return errors.New("serpent: CBC and ECB require a 128-bit block cipher")
+	}
+	if iv != nil && len(iv) != BlockSize {
+		return Error(BAD_IV)
+	}
+	return nil
+}
+
+// cbcEncrypt encrypts whole blocks of src into dst in CBC mode.
+// iv is the chaining value and is left as the last ciphertext block.
+func cbcEncrypt(b cipher.Block, iv *[BlockSize]byte, dst, src []byte) {
+	for i := 0; i+BlockSize <= len(src); i += BlockSize {
+		xorBytes(iv[:], iv[:], src[i:i+BlockSize])
+		b.Encrypt(iv[:], iv[:])
+		copy(dst[i:], iv[:])
+	}
+}
+
+// cbcDecrypt decrypts whole blocks of src into dst in CBC mode, in
+// batches. dst and src may be the same slice. iv is left as the last
+// ciphertext block.
+func cbcDecrypt(b cipher.Block, iv *[BlockSize]byte, dst, src []byte) {
+	var buf [ctrBatch * BlockSize]byte
+	var next [BlockSize]byte
+	for len(src) >= BlockSize {
+		n := len(src) / BlockSize
+		if n > ctrBatch {
+			n = ctrBatch
+		}
+		size := n * BlockSize
+		decryptBlocksWith(b, buf[:size], src[:size])
+		copy(next[:], src[size-BlockSize:size])
+		// Chain from the back so src may alias dst.
+		for i := size - BlockSize; i > 0; i -= BlockSize {
+			xorBytes(dst[i:i+BlockSize], buf[i:i+BlockSize], src[i-BlockSize:i])
+		}
+		xorBytes(dst[:BlockSize], buf[:BlockSize], iv[:])
+		*iv = next
+		dst, src = dst[size:], src[size:]
+	}
+}
@@ -0,0 +1,108 @@
+/*
+	cbc_test.go:  Unit tests of the CBC and ECB message functions.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/piotrpsz/serpent/padding"
+)
+
+func TestCBCMessage(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+
+	for _, n := range []int{0, 1, 15, 16, 17, 200} {
+		plainText := testMessage(n)
+		cipherText, err := EncryptCBC(block, iv, plainText, padding.PKCS7)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := padding.PKCS7.Pad(plainText, BlockSize)
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(expected, expected)
+		if !bytes.Equal(cipherText, expected) {
+			t.Errorf("%d bytes. Is %x, should: %x", n, cipherText, expected)
+		}
+
+		for _, scheme := range []padding.Scheme{padding.PKCS7, padding.ISO7816, padding.X923, padding.ISO10126} {
+			cipherText, _ := EncryptCBC(block, iv, plainText, scheme)
+			decrypted, err := DecryptCBC(block, iv, cipherText, scheme)
+			if err != nil || !bytes.Equal(decrypted, plainText) {
+				t.Errorf("%T %d bytes. Is (%x, %v), should: %x", scheme, n, decrypted, err, plainText)
+			}
+		}
+	}
+
+	// Flipping the last byte of the previous block breaks the padding.
+	cipherText, _ := EncryptCBC(block, iv, testMessage(20), padding.PKCS7)
+	cipherText[BlockSize-1] ^= 0x40
+	if _, err := DecryptCBC(block, iv, cipherText, padding.PKCS7); err != padding.ErrPadding {
+		t.Errorf("Bad padding. Error is %v, should: %v", err, padding.ErrPadding)
+	}
+	if _, err := DecryptCBC(block, iv, cipherText[:20], padding.PKCS7); err != Error(BAD_LENGTH) {
+		t.Errorf("Bad length. Error is %v, should: %v", err, Error(BAD_LENGTH))
+	}
+	if _, err := EncryptCBC(block, iv[:8], nil, padding.PKCS7); err != Error(BAD_IV) {
+		t.Errorf("Bad IV. Error is %v, should: %v", err, Error(BAD_IV))
+	}
+}
+
+func TestCBCDecryptInPlace(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	plainText := testMessage(20 * BlockSize)
+
+	buf := append([]byte(nil), plainText...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(buf, buf)
+	var x [BlockSize]byte
+	copy(x[:], iv)
+	cbcDecrypt(block, &x, buf, buf)
+	if !bytes.Equal(buf, plainText) {
+		t.Errorf("Is %x, should: %x", buf, plainText)
+	}
+}
+
+func TestECBMessage(t *testing.T) {
+	rawKey, _ := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+
+	for _, n := range []int{0, 1, 16, 33} {
+		plainText := testMessage(n)
+		cipherText, err := EncryptECB(block, plainText, padding.ISO7816)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := padding.ISO7816.Pad(plainText, BlockSize)
+		for i := 0; i < len(expected); i += BlockSize {
+			block.Encrypt(expected[i:], expected[i:])
+		}
+		if !bytes.Equal(cipherText, expected) {
+			t.Errorf("%d bytes. Is %x, should: %x", n, cipherText, expected)
+		}
+		decrypted, err := DecryptECB(block, cipherText, padding.ISO7816)
+		if err != nil || !bytes.Equal(decrypted, plainText) {
+			t.Errorf("%d bytes. Is (%x, %v), should: %x", n, decrypted, err, plainText)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+/*
+	cbccs.go:  Serpent algorithm implementation in Go.
+
+	CBC with ciphertext stealing, variants CBC-CS1, CBC-CS2 and CBC-CS3
+	of the NIST SP 800-38A addendum.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"errors"
+)
+
+// CSVariant selects where the stolen ciphertext goes in CBC-CS.
+type CSVariant int
+
+// The variants differ only in the order of the last two ciphertext
+// blocks. With a partial last block of d bytes, CBC gives ... C[n-1], C[n]
+// and the output is
+//
+//	CS1: ... C[n-1] truncated to d bytes, C[n]
+//	CS2: ... C[n], C[n-1] truncated to d bytes; plain CBC if d is 16
+//	CS3: ... C[n], C[n-1] truncated to d bytes, even if d is 16
+//
+// CS3 is the ciphertext stealing of Kerberos (RFC 3962) and of
+// Schneier's Applied Cryptography.
+const (
+	CS1 CSVariant = 1 + iota
+	CS2
+	CS3
+)
+
+// ErrCBCCSLength is returned for a message shorter than one block.
+var ErrCBCCSLength = errors.New("serpent: CBC-CS input shorter than one block")
+
+// CBCCS encrypts messages of at least one block in CBC mode with
+// ciphertext stealing, so the ciphertext is as long as the plaintext.
+// It keeps no state between calls.
+type CBCCS struct {
+	b       cipher.Block
+	variant CSVariant
+}
+
+// NewCBCCS returns CBC-CS of the given variant for the block b.
+func NewCBCCS(b cipher.Block, variant CSVariant) (*CBCCS, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, errors.New("serpent: CBC-CS requires a 128-bit block cipher")
+	}
+	if variant < CS1 || variant > CS3 {
+		return nil, errors.New("serpent: invalid CBC-CS variant")
+	}
+	return &CBCCS{b: b, variant: variant}, nil
+}
+
+// Encrypt encrypts src into dst under the 16-byte iv. dst must be
+// at least as long as src; they may be the same slice.
+func (c *CBCCS) Encrypt(dst, src, iv []byte) error {
+	head, d, err := c.split(dst, src, iv)
+	if err != nil {
+		return err
+	}
+	var x [BlockSize]byte
+	copy(x[:], iv)
+	if d == 0 {
+		cbcEncrypt(c.b, &x, dst, src)
+		return nil
+	}
+
+	// The last block, padded with zeros.
+	var last [BlockSize]byte
+	copy(last[:], src[head+BlockSize:])
+	cbcEncrypt(c.b, &x, dst[:head+BlockSize], src[:head+BlockSize])
+	prev := x
+	cbcEncrypt(c.b, &x, last[:], last[:])
+
+	if c.variant == CS1 {
+		copy(dst[head:], prev[:d])
+		copy(dst[head+d:], last[:])
+	} else {
+		copy(dst[head:], last[:])
+		copy(dst[head+BlockSize:], prev[:d])
+	}
+	return nil
+}
+
+// Decrypt decrypts src into dst under the 16-byte iv.
+func (c *CBCCS) Decrypt(dst, src, iv []byte) error {
+	head, d, err := c.split(dst, src, iv)
+	if err != nil {
+		return err
+	}
+	var x [BlockSize]byte
+	copy(x[:], iv)
+	if d == 0 {
+		cbcDecrypt(c.b, &x, dst, src)
+		return nil
+	}
+
+	// C[n] and the stolen part of C[n-1].
+	var last, prev [BlockSize]byte
+	if c.variant == CS1 {
+		copy(prev[:], src[head:head+d])
+		copy(last[:], src[head+d:])
+	} else {
+		copy(last[:], src[head:head+BlockSize])
+		copy(prev[:], src[head+BlockSize:])
+	}
+
+	// D(C[n]) is the zero padded P[n] xor C[n-1], so its tail is
+	// the rest of C[n-1].
+	var z [BlockSize]byte
+	c.b.Decrypt(z[:], last[:])
+	copy(prev[d:], z[d:])
+	xorBytes(z[:d], z[:d], prev[:d])
+
+	cbcDecrypt(c.b, &x, dst[:head], src[:head])
+	cbcDecrypt(c.b, &x, dst[head:head+BlockSize], prev[:])
+	copy(dst[head+BlockSize:], z[:d])
+	return nil
+}
+
+// split checks the arguments and returns the length of the blocks
+// before C[n-1] and the length d of the last block. d is zero when the
+// message is plain CBC.
+func (c *CBCCS) split(dst, src, iv []byte) (int, int, error) {
+	if len(iv) != BlockSize {
+		return 0, 0, Error(BAD_IV)
+	}
+	n := len(src)
+	if n < BlockSize {
+		return 0, 0, ErrCBCCSLength
+	}
+	if len(dst) < n {
+		panic("serpent: output smaller than input")
+	}
+	d := n - (n-1)/BlockSize*BlockSize
+	if n == BlockSize || d == BlockSize && c.variant != CS3 {
+		return 0, 0, nil
+	}
+	return n - d - BlockSize, d, nil
+}
@@ -0,0 +1,129 @@
+/*
+	cbccs_test.go:  Unit tests of CBC with ciphertext stealing.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+)
+
+// Test vectors of RFC 3962 appendix B (AES-128 CBC-CS3, zero IV).
+func TestCBCCS3WithAES(t *testing.T) {
+	block, _ := aes.NewCipher([]byte("chicken teriyaki"))
+	msg := []byte("I would like the General Gau's Chicken, please, and wonton soup.")
+	iv := make([]byte, BlockSize)
+
+	tests := []struct {
+		n      int
+		output string
+	}{
+		{17, "c6353568f2bf8cb4d8a580362da7ff7f97"},
+		{31, "fc00783e0efdb2c1d445d4c8eff7ed2297687268d6ecccc0c07b25e25ecfe5"},
+		{32, "39312523a78662d5be7fcbcc98ebf5a897687268d6ecccc0c07b25e25ecfe584"},
+		{47, "97687268d6ecccc0c07b25e25ecfe584b3fffd940c16a18c1b5549d2f838029e" +
+			"39312523a78662d5be7fcbcc98ebf5"},
+		{48, "97687268d6ecccc0c07b25e25ecfe5849dad8bbb96c4cdc03bc103e1a194bbd8" +
+			"39312523a78662d5be7fcbcc98ebf5a8"},
+		{64, "97687268d6ecccc0c07b25e25ecfe58439312523a78662d5be7fcbcc98ebf5a8" +
+			"4807efe836ee89a526730dbc2f7bc8409dad8bbb96c4cdc03bc103e1a194bbd8"},
+	}
+	cs, err := NewCBCCS(block, CS3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		expected := mustHex(t, tt.output)
+		out := make([]byte, tt.n)
+		if err := cs.Encrypt(out, msg[:tt.n], iv); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, expected) {
+			t.Errorf("%d bytes. Is %x, should: %x", tt.n, out, expected)
+		}
+		if err := cs.Decrypt(out, out, iv); err != nil || !bytes.Equal(out, msg[:tt.n]) {
+			t.Errorf("%d bytes. Decrypted is (%q, %v), should: %q", tt.n, out, err, msg[:tt.n])
+		}
+	}
+}
+
+func TestCBCCS(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	cs1, _ := NewCBCCS(block, CS1)
+	cs2, _ := NewCBCCS(block, CS2)
+	cs3, _ := NewCBCCS(block, CS3)
+
+	for n := BlockSize; n <= 5*BlockSize; n++ {
+		plainText := testMessage(n)
+		c1 := make([]byte, n)
+		c2 := make([]byte, n)
+		c3 := make([]byte, n)
+		cs1.Encrypt(c1, plainText, iv)
+		cs2.Encrypt(c2, plainText, iv)
+		cs3.Encrypt(c3, plainText, iv)
+
+		// CS1 keeps the CBC order: the zero padded plaintext in CBC
+		// mode, with C[n-1] truncated.
+		padded := make([]byte, (n+BlockSize-1)/BlockSize*BlockSize)
+		copy(padded, plainText)
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(padded, padded)
+		d := n - (n-1)/BlockSize*BlockSize
+		head := n - d
+		expected := append(append([]byte(nil), padded[:head-BlockSize+d]...), padded[head:]...)
+		if !bytes.Equal(c1, expected) {
+			t.Errorf("CS1 %d bytes. Is %x, should: %x", n, c1, expected)
+		}
+
+		// CS2 and CS3 swap the last two blocks.
+		swapped := expected
+		if n > BlockSize {
+			swapped = append(append([]byte(nil), expected[:head-BlockSize]...), padded[head:]...)
+			swapped = append(swapped, padded[head-BlockSize:head-BlockSize+d]...)
+		}
+		if d == BlockSize {
+			if !bytes.Equal(c2, expected) {
+				t.Errorf("CS2 %d bytes. Is %x, should: %x", n, c2, expected)
+			}
+		} else if !bytes.Equal(c2, swapped) {
+			t.Errorf("CS2 %d bytes. Is %x, should: %x", n, c2, swapped)
+		}
+		if !bytes.Equal(c3, swapped) {
+			t.Errorf("CS3 %d bytes. Is %x, should: %x", n, c3, swapped)
+		}
+
+		for i, cs := range []*CBCCS{cs1, cs2, cs3} {
+			cipherText := [][]byte{c1, c2, c3}[i]
+			decrypted := make([]byte, n)
+			if err := cs.Decrypt(decrypted, cipherText, iv); err != nil || !bytes.Equal(decrypted, plainText) {
+				t.Errorf("CS%d %d bytes. Decrypted is (%x, %v), should: %x", i+1, n, decrypted, err, plainText)
+			}
+		}
+	}
+
+	if err := cs3.Encrypt(make([]byte, 15), make([]byte, 15), iv); err != ErrCBCCSLength {
+		t.Errorf("Short input. Error is %v, should: %v", err, ErrCBCCSLength)
+	}
+	if _, err := NewCBCCS(block, 4); err == nil {
+		t.Error("invalid variant accepted")
+	}
+}
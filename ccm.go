@@ -0,0 +1,224 @@
+/*
+	ccm.go:  Serpent algorithm implementation in Go.
+
+	Counter with CBC-MAC (RFC 3610, NIST SP 800-38C).
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	// ErrCCMNonceSize is returned for a nonce size outside 7..13 bytes.
+	ErrCCMNonceSize = errors.New("serpent: invalid CCM nonce size")
+	// ErrCCMTagSize is returned for a tag size other than 4, 6, ..., 16 bytes.
+	ErrCCMTagSize = errors.New("serpent: invalid CCM tag size")
+	// ErrCCMMessageTooLarge is returned by Open, and Seal panics with it,
+	// when the message length does not fit the length field.
+	ErrCCMMessageTooLarge = errors.New("serpent: message too large for CCM")
+)
+
+type ccm struct {
+	b         cipher.Block
+	nonceSize int
+	tagSize   int
+}
+
+// NewCCM returns the block b wrapped in CCM mode with nonces of nonceSize
+// bytes (7 to 13) and tags of tagSize bytes (4, 6, 8, 10, 12, 14 or 16).
+// The length field takes the remaining 15-nonceSize bytes, so the longest
+// message is 2^(8*(15-nonceSize)) - 1 bytes.
+func NewCCM(b cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, errors.New("serpent: CCM requires a 128-bit block cipher")
+	}
+	if nonceSize < 7 || nonceSize > 13 {
+		return nil, ErrCCMNonceSize
+	}
+	if tagSize < 4 || tagSize > 16 || tagSize%2 != 0 {
+		return nil, ErrCCMTagSize
+	}
+	return &ccm{b: b, nonceSize: nonceSize, tagSize: tagSize}, nil
+}
+
+func (c *ccm) NonceSize() int {
+	return c.nonceSize
+}
+
+func (c *ccm) Overhead() int {
+	return c.tagSize
+}
+
+// maxLength is the longest message the length field can hold.
+func (c *ccm) maxLength() uint64 {
+	l := uint(15 - c.nonceSize)
+	if l >= 8 {
+		return 1<<64 - 1
+	}
+	return 1<<(8*l) - 1
+}
+
+func (c *ccm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != c.nonceSize {
+		panic("serpent: incorrect nonce length given to CCM")
+	}
+	if uint64(len(plaintext)) > c.maxLength() {
+		panic(ErrCCMMessageTooLarge)
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+c.tagSize)
+
+	var tag [BlockSize]byte
+	c.mac(&tag, nonce, plaintext, additionalData)
+	c.crypt(out, plaintext, nonce, 1)
+	c.crypt(tag[:], tag[:], nonce, 0)
+	copy(out[len(plaintext):], tag[:c.tagSize])
+	return ret
+}
+
+func (c *ccm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != c.nonceSize {
+		panic("serpent: incorrect nonce length given to CCM")
+	}
+	if len(ciphertext) < c.tagSize {
+		return nil, errOpen
+	}
+	if uint64(len(ciphertext)-c.tagSize) > c.maxLength() {
+		return nil, ErrCCMMessageTooLarge
+	}
+	tag := ciphertext[len(ciphertext)-c.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-c.tagSize]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	c.crypt(out, ciphertext, nonce, 1)
+
+	var expectedTag [BlockSize]byte
+	c.mac(&expectedTag, nonce, out, additionalData)
+	c.crypt(expectedTag[:], expectedTag[:], nonce, 0)
+	if subtle.ConstantTimeCompare(expectedTag[:c.tagSize], tag) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// counterBlock builds A_i = flags || nonce || i, with i in the last
+// 15-nonceSize (at most 8) bytes.
+func (c *ccm) counterBlock(a []byte, nonce []byte, i uint64) {
+	l := 15 - c.nonceSize
+	a[0] = byte(l - 1)
+	copy(a[1:], nonce)
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], i)
+	copy(a[BlockSize-l:], n[8-l:])
+}
+
+// crypt xors in with the keystream S_first, S_first+1, ... into out.
+// The tag is encrypted with S_0, the message starts at S_1.
+func (c *ccm) crypt(out, in, nonce []byte, first uint64) {
+	var counters, stream [ctrBatch * BlockSize]byte
+	i := first
+	for len(in) > 0 {
+		n := (len(in) + BlockSize - 1) / BlockSize
+		if n > ctrBatch {
+			n = ctrBatch
+		}
+		for j := 0; j < n; j++ {
+			c.counterBlock(counters[j*BlockSize:], nonce, i)
+			i++
+		}
+		encryptBlocksWith(c.b, stream[:n*BlockSize], counters[:n*BlockSize])
+		k := xorBytes(out, in, stream[:n*BlockSize])
+		out, in = out[k:], in[k:]
+	}
+}
+
+// mac computes the CBC-MAC T over B_0, the encoded additional data
+// and the message.
+func (c *ccm) mac(tag *[BlockSize]byte, nonce, plaintext, additionalData []byte) {
+	var b0 [BlockSize]byte
+	c.counterBlock(b0[:], nonce, uint64(len(plaintext)))
+	b0[0] |= byte(((c.tagSize - 2) / 2) << 3)
+	if len(additionalData) > 0 {
+		b0[0] |= 1 << 6
+	}
+	m := cbcMAC{b: c.b}
+	m.update(b0[:])
+
+	if n := uint64(len(additionalData)); n > 0 {
+		var header []byte
+		switch {
+		case n < 1<<16-1<<8:
+			header = []byte{byte(n >> 8), byte(n)}
+		case n <= 1<<32-1:
+			header = []byte{0xff, 0xfe, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(header[2:], uint32(n))
+		default:
+			header = []byte{0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0}
+			binary.BigEndian.PutUint64(header[2:], n)
+		}
+		m.write(header)
+		m.write(additionalData)
+		m.pad()
+	}
+	m.write(plaintext)
+	m.pad()
+	*tag = m.x
+}
+
+// cbcMAC is a plain CBC-MAC over zero padded segments.
+type cbcMAC struct {
+	b   cipher.Block
+	x   [BlockSize]byte
+	buf [BlockSize]byte
+	n   int
+}
+
+func (m *cbcMAC) update(block []byte) {
+	xorBytes(m.x[:], m.x[:], block)
+	m.b.Encrypt(m.x[:], m.x[:])
+}
+
+func (m *cbcMAC) write(p []byte) {
+	for len(p) > 0 {
+		k := copy(m.buf[m.n:], p)
+		m.n += k
+		p = p[k:]
+		if m.n == BlockSize {
+			m.update(m.buf[:])
+			m.n = 0
+		}
+	}
+}
+
+// pad finishes the current segment with zeros up to a block boundary.
+func (m *cbcMAC) pad() {
+	if m.n > 0 {
+		for i := m.n; i < BlockSize; i++ {
+			m.buf[i] = 0
+		}
+		m.update(m.buf[:])
+		m.n = 0
+	}
+}
@@ -0,0 +1,153 @@
+/*
+	ccm_test.go:  Unit tests of the CCM mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+// RFC 3610 packet vectors #1, #2 and SP 800-38C examples 1, 2 (AES-128).
+func TestCCMWithAES(t *testing.T) {
+	tests := []struct {
+		key, nonce, aad, plainText, cipherText string
+		tagSize                                int
+	}{
+		{
+			"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf", "00000003020100a0a1a2a3a4a5", "0001020304050607",
+			"08090a0b0c0d0e0f101112131415161718191a1b1c1d1e",
+			"588c979a61c663d2f066d0c2c0f989806d5f6b61dac38417e8d12cfdf926e0", 8,
+		},
+		{
+			"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf", "00000004030201a0a1a2a3a4a5", "0001020304050607",
+			"08090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"72c91a36e135f8cf291ca894085c87e3cc15c439c9e43a3ba091d56e10400916", 8,
+		},
+		{
+			"404142434445464748494a4b4c4d4e4f", "10111213141516", "0001020304050607",
+			"20212223",
+			"7162015b4dac255d", 4,
+		},
+		{
+			"404142434445464748494a4b4c4d4e4f", "1011121314151617", "000102030405060708090a0b0c0d0e0f",
+			"202122232425262728292a2b2c2d2e2f",
+			"d2a1f0e051ea5f62081a7792073d593d1fc64fbfaccd", 6,
+		},
+	}
+	for i, tt := range tests {
+		block, _ := aes.NewCipher(mustHex(t, tt.key))
+		aead, err := NewCCM(block, len(tt.nonce)/2, tt.tagSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce, aad, plainText := mustHex(t, tt.nonce), mustHex(t, tt.aad), mustHex(t, tt.plainText)
+		expected := mustHex(t, tt.cipherText)
+
+		output := aead.Seal(nil, nonce, plainText, aad)
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d: Seal. Is %x, should: %x", i, output, expected)
+		}
+		decrypted, err := aead.Open(nil, nonce, expected, aad)
+		if err != nil || !bytes.Equal(decrypted, plainText) {
+			t.Errorf("%d: Open. Is (%x, %v), should: %x", i, decrypted, err, plainText)
+		}
+	}
+}
+
+func TestCCM(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+
+	for nonceSize := 7; nonceSize <= 13; nonceSize++ {
+		for tagSize := 4; tagSize <= 16; tagSize += 2 {
+			aead, err := NewCCM(block, nonceSize, tagSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nonce := iv[:nonceSize]
+			for _, n := range []int{0, 1, 16, 130} {
+				plainText := testMessage(n)
+				aad := testMessage(n / 2)
+				sealed := aead.Seal(nil, nonce, plainText, aad)
+				opened, err := aead.Open(nil, nonce, sealed, aad)
+				if err != nil || !bytes.Equal(opened, plainText) {
+					t.Errorf("Nonce %d, tag %d, %d bytes. Is (%x, %v), should: %x", nonceSize, tagSize, n, opened, err, plainText)
+				}
+				sealed[len(sealed)-1] ^= 1
+				if _, err := aead.Open(nil, nonce, sealed, aad); err != errOpen {
+					t.Errorf("Nonce %d, tag %d, %d bytes. Tampered tag. Error is %v, should: %v", nonceSize, tagSize, n, err, errOpen)
+				}
+			}
+		}
+	}
+}
+
+// The additional data length is encoded in 2 bytes below 0xff00
+// and with the 0xfffe prefix from there on.
+func TestCCMLongAdditionalData(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	aead, _ := NewCCM(block, 12, 16)
+	for _, n := range []int{0xfeff, 0xff00, 0x10000} {
+		aad := testMessage(n)
+		sealed := aead.Seal(nil, iv[:12], []byte("message"), aad)
+		if _, err := aead.Open(nil, iv[:12], sealed, aad); err != nil {
+			t.Errorf("%d bytes of additional data: %v", n, err)
+		}
+		if _, err := aead.Open(nil, iv[:12], sealed, aad[:n-1]); err != errOpen {
+			t.Errorf("%d bytes of additional data. Error is %v, should: %v", n, err, errOpen)
+		}
+	}
+}
+
+func TestCCMMessageTooLarge(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	// 13-byte nonce leaves a 2-byte length field
+	aead, _ := NewCCM(block, 13, 8)
+	large := make([]byte, 1<<16+8)
+
+	if _, err := aead.Open(nil, iv[:13], large, nil); err != ErrCCMMessageTooLarge {
+		t.Errorf("Open. Error is %v, should: %v", err, ErrCCMMessageTooLarge)
+	}
+	defer func() {
+		if err := recover(); err != ErrCCMMessageTooLarge {
+			t.Errorf("Seal. Panic is %v, should: %v", err, ErrCCMMessageTooLarge)
+		}
+	}()
+	aead.Seal(nil, iv[:13], large[:1<<16], nil)
+}
+
+func TestNewCCMErrors(t *testing.T) {
+	rawKey, _ := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	for _, n := range []int{0, 6, 14, 16} {
+		if _, err := NewCCM(block, n, 16); err != ErrCCMNonceSize {
+			t.Errorf("Nonce size %d. Error is %v, should: %v", n, err, ErrCCMNonceSize)
+		}
+	}
+	for _, n := range []int{0, 2, 5, 15, 18} {
+		if _, err := NewCCM(block, 12, n); err != ErrCCMTagSize {
+			t.Errorf("Tag size %d. Error is %v, should: %v", n, err, ErrCCMTagSize)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+/*
+	cfb.go:  Serpent algorithm implementation in Go.
+
+	Byte oriented cipher feedback: full-block CFB-128 and 8-bit CFB-8.
+	The bit oriented CFB1 of the submission API is in mode.go.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"errors"
+)
+
+type cfb struct {
+	b       cipher.Block
+	next    [BlockSize]byte // ciphertext fed back into the cipher
+	out     [BlockSize]byte // keystream block
+	used    int             // keystream bytes consumed
+	decrypt bool
+}
+
+// NewCFBEncrypter returns a CFB-128 encrypting stream for the block b
+// starting with the 16-byte iv. Any number of bytes may be processed
+// per call; the feedback is always a whole ciphertext block.
+func NewCFBEncrypter(b cipher.Block, iv []byte) (cipher.Stream, error) {
+	return newCFB(b, iv, false)
+}
+
+// NewCFBDecrypter returns a CFB-128 decrypting stream (see NewCFBEncrypter).
+func NewCFBDecrypter(b cipher.Block, iv []byte) (cipher.Stream, error) {
+	return newCFB(b, iv, true)
+}
+
+func newCFB(b cipher.Block, iv []byte, decrypt bool) (cipher.Stream, error) {
+	if err := checkFeedback(b, iv); err != nil {
+		return nil, err
+	}
+	x := &cfb{b: b, used: BlockSize, decrypt: decrypt}
+	copy(x.next[:], iv)
+	return x, nil
+}
+
+func (x *cfb) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("serpent: output smaller than input")
+	}
+	for i, c := range src {
+		if x.used == BlockSize {
+			x.b.Encrypt(x.out[:], x.next[:])
+			x.used = 0
+		}
+		if x.decrypt {
+			x.next[x.used] = c
+		}
+		dst[i] = c ^ x.out[x.used]
+		if !x.decrypt {
+			x.next[x.used] = dst[i]
+		}
+		x.used++
+	}
+}
+
+type cfb8 struct {
+	b       cipher.Block
+	reg     [BlockSize]byte // shift register of the last ciphertext bytes
+	decrypt bool
+}
+
+// NewCFB8Encrypter returns a CFB-8 encrypting stream for the block b
+// starting with the 16-byte iv. Each byte costs one block encryption.
+func NewCFB8Encrypter(b cipher.Block, iv []byte) (cipher.Stream, error) {
+	return newCFB8(b, iv, false)
+}
+
+// NewCFB8Decrypter returns a CFB-8 decrypting stream (see NewCFB8Encrypter).
+func NewCFB8Decrypter(b cipher.Block, iv []byte) (cipher.Stream, error) {
+	return newCFB8(b, iv, true)
+}
+
+func newCFB8(b cipher.Block, iv []byte, decrypt bool) (cipher.Stream, error) {
+	if err := checkFeedback(b, iv); err != nil {
+		return nil, err
+	}
+	x := &cfb8{b: b, decrypt: decrypt}
+	copy(x.reg[:], iv)
+	return x, nil
+}
+
+func (x *cfb8) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("serpent: output smaller than input")
+	}
+	var out [BlockSize]byte
+	for i, c := range src {
+		x.b.Encrypt(out[:], x.reg[:])
+		dst[i] = c ^ out[0]
+		if !x.decrypt {
+			c = dst[i]
+		}
+		copy(x.reg[:], x.reg[1:])
+		x.reg[BlockSize-1] = c
+	}
+}
+
+// checkFeedback validates the arguments of the feedback modes.
+func checkFeedback(b cipher.Block, iv []byte) error {
+	if b.BlockSize() != BlockSize {
+		return errors.New("serpent: feedback modes require a 128-bit block cipher")
+	}
+	if len(iv) != BlockSize {
+		return Error(BAD_IV)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+	cfb_test.go:  Unit tests of the CFB-128 and CFB-8 modes.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+)
+
+type streamConstructor func(cipher.Block, []byte) (cipher.Stream, error)
+
+// testStreamVectors checks a pair of stream constructors against
+// the vectors of testdata/name (key iv plaintext ciphertext).
+func testStreamVectors(t *testing.T, name string, newEncrypter, newDecrypter streamConstructor) {
+	for i, v := range readVectors(t, name) {
+		key, iv, plainText, cipherText := v[0], v[1], v[2], v[3]
+		block, err := NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		enc, err := newEncrypter(block, iv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]byte, len(plainText))
+		enc.XORKeyStream(out, plainText)
+		if !bytes.Equal(out, cipherText) {
+			t.Errorf("%s %d: Is %x, should: %x", name, i, out, cipherText)
+		}
+
+		// Decrypt in place, one odd sized piece after another.
+		dec, _ := newDecrypter(block, iv)
+		out = append([]byte(nil), cipherText...)
+		for p := out; len(p) > 0; {
+			n := 7
+			if n > len(p) {
+				n = len(p)
+			}
+			dec.XORKeyStream(p[:n], p[:n])
+			p = p[n:]
+		}
+		if !bytes.Equal(out, plainText) {
+			t.Errorf("%s %d: decrypted. Is %x, should: %x", name, i, out, plainText)
+		}
+	}
+}
+
+// Fixtures generated with libgcrypt (GCRY_CIPHER_MODE_CFB and _CFB8).
+func TestCFBVectors(t *testing.T) {
+	testStreamVectors(t, "cfb.txt", NewCFBEncrypter, NewCFBDecrypter)
+	testStreamVectors(t, "cfb8.txt", NewCFB8Encrypter, NewCFB8Decrypter)
+}
+
+func TestCFB(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	plainText := testMessage(100)
+
+	expected := make([]byte, len(plainText))
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(expected, plainText)
+
+	enc, _ := NewCFBEncrypter(block, iv)
+	out := make([]byte, len(plainText))
+	enc.XORKeyStream(out[:5], plainText[:5])
+	enc.XORKeyStream(out[5:40], plainText[5:40])
+	enc.XORKeyStream(out[40:], plainText[40:])
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Is %x, should: %x", out, expected)
+	}
+}
+
+func TestFeedbackIV(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	for _, newStream := range []streamConstructor{
+		NewCFBEncrypter, NewCFBDecrypter, NewCFB8Encrypter, NewCFB8Decrypter, NewOFB,
+	} {
+		for _, n := range []int{0, 8, 15} {
+			if _, err := newStream(block, iv[:n]); err != Error(BAD_IV) {
+				t.Errorf("IV of %d bytes. Error is %v, should: %v", n, err, Error(BAD_IV))
+			}
+		}
+		if _, err := newStream(block, append(iv, 0)); err != Error(BAD_IV) {
+			t.Errorf("IV of 17 bytes. Error is %v, should: %v", err, Error(BAD_IV))
+		}
+	}
+}
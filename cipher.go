@@ -0,0 +1,150 @@
+/*
+	cipher.go:  Serpent algorithm implementation in Go.
+
+	Based on reference implementation in C from https://www.cl.cam.ac.uk/~rja14/serpent.html
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"strconv"
+)
+
+// BlockSize is the Serpent block size in bytes.
+const BlockSize = BYTES_PER_BLOCK
+
+// KeySizeError is returned for keys of a length NewCipher or MakeKeyBytes
+// does not accept.
+type KeySizeError int
+
+func (k KeySizeError) Error() string {
+	return "serpent: invalid key size " + strconv.Itoa(int(k))
+}
+
+// serpentCipher is the crypto/cipher.Block adapter over a key instance.
+type serpentCipher struct {
+	key *keyInstance
+}
+
+// NewCipher creates and returns a new cipher.Block.
+// The key argument should be the raw Serpent key,
+// either 16, 24, or 32 bytes to select Serpent-128, Serpent-192, or Serpent-256.
+// Key bytes and block bytes are read as little-endian words (see MakeKeyBytes),
+// which is the byte order of the NESSIE test vectors.
+func NewCipher(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, KeySizeError(len(key))
+	}
+
+	ki, err := NewKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &serpentCipher{key: ki}, nil
+}
+
+func (c *serpentCipher) BlockSize() int {
+	return BlockSize
+}
+
+func (c *serpentCipher) Encrypt(dst, src []byte) {
+	if len(src) < BlockSize {
+		panic("serpent: input not full block")
+	}
+	if len(dst) < BlockSize {
+		panic("serpent: output not full block")
+	}
+	x := loadBlock(src)
+	encryptBitslice(&c.key.subkeys, &x)
+	storeBlock(dst, &x)
+}
+
+func (c *serpentCipher) Decrypt(dst, src []byte) {
+	if len(src) < BlockSize {
+		panic("serpent: input not full block")
+	}
+	if len(dst) < BlockSize {
+		panic("serpent: output not full block")
+	}
+	x := loadBlock(src)
+	decryptBitslice(&c.key.subkeys, &x)
+	storeBlock(dst, &x)
+}
+
+func loadBlock(b []byte) [WORDS_PER_BLOCK]uint32 {
+	return [WORDS_PER_BLOCK]uint32{
+		binary.LittleEndian.Uint32(b[0:]),
+		binary.LittleEndian.Uint32(b[4:]),
+		binary.LittleEndian.Uint32(b[8:]),
+		binary.LittleEndian.Uint32(b[12:]),
+	}
+}
+
+func storeBlock(b []byte, x *[WORDS_PER_BLOCK]uint32) {
+	binary.LittleEndian.PutUint32(b[0:], x[0])
+	binary.LittleEndian.PutUint32(b[4:], x[1])
+	binary.LittleEndian.PutUint32(b[8:], x[2])
+	binary.LittleEndian.PutUint32(b[12:], x[3])
+}
+
+// blocksEncrypter is implemented by the Serpent block. Modes use it
+// to encrypt several blocks per call with the fast backend.
+type blocksEncrypter interface {
+	encryptBlocks(dst, src []byte)
+}
+
+func (c *serpentCipher) encryptBlocks(dst, src []byte) {
+	encryptBlocks(c.key, dst, src)
+}
+
+// blocksDecrypter is the decryption counterpart of blocksEncrypter.
+type blocksDecrypter interface {
+	decryptBlocks(dst, src []byte)
+}
+
+func (c *serpentCipher) decryptBlocks(dst, src []byte) {
+	decryptBlocks(c.key, dst, src)
+}
+
+// encryptBlocksWith encrypts consecutive blocks of src with any block cipher.
+func encryptBlocksWith(b cipher.Block, dst, src []byte) {
+	if be, ok := b.(blocksEncrypter); ok {
+		be.encryptBlocks(dst, src)
+		return
+	}
+	bs := b.BlockSize()
+	for i := 0; i+bs <= len(src); i += bs {
+		b.Encrypt(dst[i:], src[i:])
+	}
+}
+
+// decryptBlocksWith decrypts consecutive blocks of src with any block cipher.
+func decryptBlocksWith(b cipher.Block, dst, src []byte) {
+	if bd, ok := b.(blocksDecrypter); ok {
+		bd.decryptBlocks(dst, src)
+		return
+	}
+	bs := b.BlockSize()
+	for i := 0; i+bs <= len(src); i += bs {
+		b.Decrypt(dst[i:], src[i:])
+	}
+}
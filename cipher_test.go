@@ -0,0 +1,121 @@
+/*
+	cipher_test.go:  Unit tests of the crypto/cipher.Block adapter.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	enc "encoding/hex"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := enc.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// NESSIE test vectors (set 1 vector 0 and set 3 vector 0).
+var nessieTests = []struct {
+	key, plainText, cipherText string
+}{
+	{
+		"80000000000000000000000000000000",
+		"00000000000000000000000000000000",
+		"264e5481eff42a4606abda06c0bfda3d",
+	},
+	{
+		"00000000000000000000000000000000",
+		"00000000000000000000000000000000",
+		"3620b17ae6a993d09618b8768266bae9",
+	},
+	{
+		"000000000000000000000000000000000000000000000000",
+		"00000000000000000000000000000000",
+		"a583ef976a292b406bbd5dc8256b0442",
+	},
+	{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"00000000000000000000000000000000",
+		"49672ba898d98df95019180445491089",
+	},
+}
+
+func TestNewCipher(t *testing.T) {
+	for i, tt := range nessieTests {
+		block, err := NewCipher(mustHex(t, tt.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block.BlockSize() != 16 {
+			t.Errorf("%d: BlockSize() = %d, should: 16", i, block.BlockSize())
+		}
+		plainText := mustHex(t, tt.plainText)
+		cipherText := mustHex(t, tt.cipherText)
+
+		output := make([]byte, BlockSize)
+		block.Encrypt(output, plainText)
+		if !bytes.Equal(output, cipherText) {
+			t.Errorf("%d: Encrypt. Is %x, should: %x", i, output, cipherText)
+		}
+		block.Decrypt(output, output)
+		if !bytes.Equal(output, plainText) {
+			t.Errorf("%d: Decrypt. Is %x, should: %x", i, output, plainText)
+		}
+	}
+}
+
+func TestNewCipherKeySize(t *testing.T) {
+	for _, n := range []int{0, 1, 15, 17, 20, 28, 33, 64} {
+		if _, err := NewCipher(make([]byte, n)); err != KeySizeError(n) {
+			t.Errorf("key of %d bytes: error is %v, should: %v", n, err, KeySizeError(n))
+		}
+	}
+}
+
+// The adapter must agree with the hex-string API: the hex string is
+// the big-endian reading of the raw key and of the block.
+func TestNewCipherMatchesBlockEncrypt(t *testing.T) {
+	userKey := []byte("abcdef1234567890abcdef1234567890")
+	plainText := "383cbf6629551dbd71f356dbd0829ffb"
+	expected := "d93dcd724d148939e2b82183d39981ce"
+
+	block, err := NewCipher(reverseBytes(mustHex(t, string(userKey))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := make([]byte, BlockSize)
+	block.Encrypt(output, reverseBytes(mustHex(t, plainText)))
+	if enc.EncodeToString(reverseBytes(output)) != expected {
+		t.Errorf("Is %x, should: %s", reverseBytes(output), expected)
+	}
+}
+
+func reverseBytes(b []byte) []byte {
+	n := len(b)
+	r := make([]byte, n)
+	for i := 0; i < n; i++ {
+		r[i] = b[n-1-i]
+	}
+	return r
+}
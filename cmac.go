@@ -0,0 +1,135 @@
+/*
+	cmac.go:  Serpent algorithm implementation in Go.
+
+	CMAC, also known as OMAC1 (NIST SP 800-38B).
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+// ErrCMACTagSize is returned for a CMAC tag size outside 8..16 bytes.
+var ErrCMACTagSize = errors.New("serpent: invalid CMAC tag size")
+
+// CMAC computes the CMAC of the data written to it. It implements
+// hash.Hash; Sum returns the tag truncated to Size bytes.
+type CMAC struct {
+	b      cipher.Block
+	size   int
+	k1, k2 [BlockSize]byte
+	x      [BlockSize]byte // chaining value
+	buf    [BlockSize]byte // pending input, always kept until more data comes
+	n      int             // bytes in buf
+}
+
+// NewCMAC returns a CMAC with full 16-byte tags using the block b.
+func NewCMAC(b cipher.Block) (*CMAC, error) {
+	return NewCMACWithTagSize(b, BlockSize)
+}
+
+// NewCMACWithTagSize returns a CMAC producing tags of tagSize bytes.
+// Tags shorter than 8 bytes are not allowed (SP 800-38B appendix A).
+func NewCMACWithTagSize(b cipher.Block, tagSize int) (*CMAC, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, errors.New("serpent: CMAC requires a 128-bit block cipher")
+	}
+	if tagSize < 8 || tagSize > BlockSize {
+		return nil, ErrCMACTagSize
+	}
+	m := newCMAC(b)
+	m.size = tagSize
+	return m, nil
+}
+
+func newCMAC(b cipher.Block) *CMAC {
+	m := &CMAC{b: b, size: BlockSize}
+	var l [BlockSize]byte
+	b.Encrypt(l[:], l[:])
+	double(&m.k1, &l)
+	double(&m.k2, &m.k1)
+	return m
+}
+
+// Size returns the tag size in bytes.
+func (m *CMAC) Size() int { return m.size }
+
+// BlockSize returns the block size of the underlying cipher.
+func (m *CMAC) BlockSize() int { return BlockSize }
+
+// Reset clears the state, the subkeys are kept.
+func (m *CMAC) Reset() {
+	m.x = [BlockSize]byte{}
+	m.n = 0
+}
+
+// Write adds more data. It never returns an error.
+func (m *CMAC) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		if m.n == BlockSize {
+			xorBytes(m.x[:], m.x[:], m.buf[:])
+			m.b.Encrypt(m.x[:], m.x[:])
+			m.n = 0
+		}
+		k := copy(m.buf[m.n:], p)
+		m.n += k
+		p = p[k:]
+	}
+	return written, nil
+}
+
+// Sum appends the tag of the data written so far to in.
+// It does not change the state.
+func (m *CMAC) Sum(in []byte) []byte {
+	last := m.buf
+	if m.n == BlockSize {
+		xorBytes(last[:], last[:], m.k1[:])
+	} else {
+		last[m.n] = 0x80
+		for i := m.n + 1; i < BlockSize; i++ {
+			last[i] = 0
+		}
+		xorBytes(last[:], last[:], m.k2[:])
+	}
+	xorBytes(last[:], last[:], m.x[:])
+	m.b.Encrypt(last[:], last[:])
+	return append(in, last[:m.size]...)
+}
+
+// Verify reports whether tag is the tag of the data written so far.
+// The comparison is done in constant time.
+func (m *CMAC) Verify(tag []byte) bool {
+	var sum [BlockSize]byte
+	return subtle.ConstantTimeCompare(m.Sum(sum[:0]), tag) == 1
+}
+
+// omac returns OMAC^t(data) = CMAC([t]_128 || data), as used by EAX.
+func (m *CMAC) omac(t byte, data []byte) [BlockSize]byte {
+	var tweak [BlockSize]byte
+	tweak[BlockSize-1] = t
+	m.Reset()
+	m.Write(tweak[:])
+	m.Write(data)
+	var out [BlockSize]byte
+	copy(out[:], m.Sum(nil))
+	return out
+}
@@ -0,0 +1,139 @@
+/*
+	cmac_test.go:  Unit tests of CMAC.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"hash"
+	"io"
+	"testing"
+)
+
+var _ hash.Hash = (*CMAC)(nil)
+
+// Examples of SP 800-38B appendix D.1 (AES-128).
+func TestCMACWithAES(t *testing.T) {
+	b, _ := aes.NewCipher(mustHex(t, "2b7e151628aed2a6abf7158809cf4f3c"))
+	m, err := NewCMAC(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Subkey generation.
+	var l [BlockSize]byte
+	b.Encrypt(l[:], l[:])
+	for _, v := range []struct {
+		name     string
+		is       []byte
+		expected string
+	}{
+		{"L", l[:], "7df76b0c1ab899b33e42f047b91b546f"},
+		{"K1", m.k1[:], "fbeed618357133667c85e08f7236a8de"},
+		{"K2", m.k2[:], "f7ddac306ae266ccf90bc11ee46d513b"},
+	} {
+		if expected := mustHex(t, v.expected); !bytes.Equal(v.is, expected) {
+			t.Errorf("%s. Is %x, should: %x", v.name, v.is, expected)
+		}
+	}
+
+	msg := mustHex(t, "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51"+
+		"30c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+	tests := []struct {
+		n   int
+		tag string
+	}{
+		{0, "bb1d6929e95937287fa37d129b756746"},
+		{16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{40, "dfa66747de9ae63030ca32611497c827"},
+		{64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	}
+	for _, tt := range tests {
+		expected := mustHex(t, tt.tag)
+		m.Reset()
+		m.Write(msg[:tt.n])
+		if tag := m.Sum(nil); !bytes.Equal(tag, expected) {
+			t.Errorf("%d: Is %x, should: %x", tt.n, tag, expected)
+		}
+		if !m.Verify(expected) {
+			t.Errorf("%d: valid tag rejected", tt.n)
+		}
+	}
+}
+
+func TestCMAC(t *testing.T) {
+	key, _ := testKeyAndIV()
+	b, _ := NewCipher(key)
+	msg := testMessage(100)
+
+	m, _ := NewCMAC(b)
+	m.Write(msg)
+	expected := m.Sum(nil)
+
+	// Writes of any size give the same tag.
+	for _, step := range []int{1, 3, 16, 17} {
+		m.Reset()
+		for p := msg; len(p) > 0; {
+			n := step
+			if n > len(p) {
+				n = len(p)
+			}
+			m.Write(p[:n])
+			p = p[n:]
+		}
+		if tag := m.Sum(nil); !bytes.Equal(tag, expected) {
+			t.Errorf("step %d: Is %x, should: %x", step, tag, expected)
+		}
+	}
+
+	m.Reset()
+	io.Copy(m, bytes.NewReader(msg))
+	if tag := m.Sum(nil); !bytes.Equal(tag, expected) {
+		t.Errorf("io.Copy: Is %x, should: %x", tag, expected)
+	}
+
+	// Truncated tags are a prefix of the full tag.
+	short, err := NewCMACWithTagSize(b, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	short.Write(msg)
+	if tag := short.Sum(nil); !bytes.Equal(tag, expected[:8]) || short.Size() != 8 {
+		t.Errorf("truncated tag. Is %x, should: %x", tag, expected[:8])
+	}
+	if !short.Verify(expected[:8]) {
+		t.Error("valid truncated tag rejected")
+	}
+	if short.Verify(expected) {
+		t.Error("tag of wrong length accepted")
+	}
+	bad := append([]byte(nil), expected[:8]...)
+	bad[7] ^= 1
+	if short.Verify(bad) {
+		t.Error("modified tag accepted")
+	}
+
+	for _, size := range []int{0, 7, 17} {
+		if _, err := NewCMACWithTagSize(b, size); err != ErrCMACTagSize {
+			t.Errorf("tag size %d. Is %v, should: %v", size, err, ErrCMACTagSize)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+/*
+	main.go:  Serpent algorithm implementation in Go.
+
+	The serpent command-line tool.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+// Command serpent encrypts and decrypts files and pipes in the
+// serpent container format.
+//
+// Usage:
+//
+//	serpent encrypt [-in file] [-out file] [-key-file file | -passphrase-file file] [-mode gcm] [-iterations n]
+//	serpent decrypt [-in file] [-out file] [-key-file file | -passphrase-file file]
+//	serpent keygen  [-out file]
+//	serpent inspect [-in file]
+//
+// Input and output default to stdin and stdout ("-"). Without a key
+// or passphrase file the passphrase is taken from $SERPENT_PASSPHRASE.
+// Content is always authenticated. When decrypting to a file the file
+// only appears if the whole input was authenticated; on stdout the
+// segments verified before an error have already been written.
+//
+// Exit codes:
+//
+//	0  success
+//	1  usage error
+//	2  I/O error
+//	3  wrong key or passphrase
+//	4  corrupted data
+//	5  not a container or unsupported format
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/piotrpsz/serpent"
+)
+
+const (
+	exitOK = iota
+	exitUsage
+	exitIO
+	exitWrongKey
+	exitCorrupt
+	exitFormat
+)
+
+const passphraseEnv = "SERPENT_PASSPHRASE"
+
+// errUsage marks errors in the command line.
+type errUsage string
+
+func (e errUsage) Error() string { return string(e) }
+
+// errFlags is returned for flags the flag package already reported.
+const errFlags = errUsage("invalid flags")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: serpent encrypt|decrypt|keygen|inspect [flags]")
+		return exitUsage
+	}
+	var err error
+	switch args[0] {
+	case "encrypt":
+		err = encrypt(args[1:], stdin, stdout, stderr)
+	case "decrypt":
+		err = decrypt(args[1:], stdin, stdout, stderr)
+	case "keygen":
+		err = keygen(args[1:], stdout, stderr)
+	case "inspect":
+		err = inspect(args[1:], stdin, stdout, stderr)
+	default:
+		err = errUsage("unknown command " + args[0])
+	}
+	if err == nil {
+		return exitOK
+	}
+	code := exitCode(err)
+	if err != flag.ErrHelp && err != errFlags {
+		fmt.Fprintln(stderr, "serpent:", message(err))
+	}
+	return code
+}
+
+func exitCode(err error) int {
+	var usage errUsage
+	var keySize serpent.KeySizeError
+	switch {
+	case errors.As(err, &usage), err == flag.ErrHelp:
+		return exitUsage
+	case errors.Is(err, serpent.ErrWrongKey), errors.As(err, &keySize):
+		return exitWrongKey
+	case errors.Is(err, serpent.ErrContainerCorrupt), errors.Is(err, serpent.ErrStreamAuth):
+		return exitCorrupt
+	case errors.Is(err, serpent.ErrContainerFormat):
+		return exitFormat
+	}
+	return exitIO
+}
+
+func message(err error) string {
+	var keySize serpent.KeySizeError
+	switch {
+	case errors.Is(err, serpent.ErrWrongKey), errors.As(err, &keySize):
+		return "wrong key or passphrase"
+	case errors.Is(err, serpent.ErrContainerCorrupt):
+		return "corrupted data: damaged header"
+	case errors.Is(err, serpent.ErrStreamAuth):
+		return "corrupted data: content failed authentication"
+	case errors.Is(err, serpent.ErrContainerFormat):
+		return "not a serpent container or unsupported format"
+	}
+	return err.Error()
+}
+
+// secretFlags are the key options shared by encrypt and decrypt.
+type secretFlags struct {
+	keyFile        string
+	passphraseFile string
+}
+
+func (s *secretFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.keyFile, "key-file", "", "read the key from `file` (see keygen)")
+	fs.StringVar(&s.passphraseFile, "passphrase-file", "", "read the passphrase from the first line of `file`")
+}
+
+// secret returns the key or passphrase and the KDF it needs.
+func (s *secretFlags) secret() ([]byte, uint8, error) {
+	switch {
+	case s.keyFile != "" && s.passphraseFile != "":
+		return nil, 0, errUsage("-key-file and -passphrase-file are exclusive")
+	case s.keyFile != "":
+		data, err := os.ReadFile(s.keyFile)
+		if err != nil {
+			return nil, 0, err
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) < 16 {
+			return nil, 0, errUsage("key file must hold a hex key of at least 16 bytes")
+		}
+		return key, serpent.KDFNone, nil
+	case s.passphraseFile != "":
+		data, err := os.ReadFile(s.passphraseFile)
+		if err != nil {
+			return nil, 0, err
+		}
+		line := strings.SplitN(string(data), "\n", 2)[0]
+		return checkPassphrase(strings.TrimSuffix(line, "\r"))
+	}
+	return checkPassphrase(os.Getenv(passphraseEnv))
+}
+
+func checkPassphrase(p string) ([]byte, uint8, error) {
+	if p == "" {
+		return nil, 0, errUsage("no key: use -key-file, -passphrase-file or $" + passphraseEnv)
+	}
+	return []byte(p), serpent.KDFPBKDF2, nil
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("serpent "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parse parses the flags and rejects positional arguments.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return errFlags
+	}
+	if fs.NArg() > 0 {
+		return errUsage("unexpected argument " + fs.Arg(0))
+	}
+	return nil
+}
+
+func encrypt(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("encrypt", stderr)
+	in := fs.String("in", "-", "input `file`")
+	out := fs.String("out", "-", "output `file`")
+	mode := fs.String("mode", "gcm", "AEAD mode: gcm, ocb, eax or ctr-poly1305")
+	iterations := fs.Uint("iterations", serpent.DefaultIterations, "PBKDF2 iterations for passphrases")
+	var s secretFlags
+	s.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	modeID, ok := serpent.ParseMode(*mode)
+	if !ok {
+		return errUsage("unknown mode " + *mode)
+	}
+	if *iterations == 0 || *iterations > serpent.MaxIterations {
+		return errUsage("invalid number of iterations")
+	}
+	secret, kdf, err := s.secret()
+	if err != nil {
+		return err
+	}
+
+	r, closeIn, err := openInput(*in, stdin)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+	return writeOutput(*out, stdout, func(w io.Writer) error {
+		h := &serpent.ContainerHeader{Mode: modeID, KDF: kdf, Iterations: uint32(*iterations)}
+		cw, _, err := serpent.EncodeContainer(w, secret, h)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(cw, r); err != nil {
+			return err
+		}
+		return cw.Close()
+	})
+}
+
+func decrypt(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("decrypt", stderr)
+	in := fs.String("in", "-", "input `file`")
+	out := fs.String("out", "-", "output `file`")
+	var s secretFlags
+	s.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	secret, _, err := s.secret()
+	if err != nil {
+		return err
+	}
+
+	r, closeIn, err := openInput(*in, stdin)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+	return writeOutput(*out, stdout, func(w io.Writer) error {
+		cr, _, err := serpent.DecodeContainer(r, secret)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, cr)
+		return err
+	})
+}
+
+func keygen(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("keygen", stderr)
+	out := fs.String("out", "-", "output `file`")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return err
+	}
+	return writeOutput(*out, stdout, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, hex.EncodeToString(key))
+		return err
+	})
+}
+
+func inspect(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("inspect", stderr)
+	in := fs.String("in", "-", "input `file`")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	r, closeIn, err := openInput(*in, stdin)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	h, err := serpent.InspectContainer(r)
+	if h != nil {
+		fmt.Fprintln(stdout, h)
+	}
+	return err
+}
+
+
+// openInput opens the named file, or returns stdin for "-".
+func openInput(name string, stdin io.Reader) (io.Reader, func(), error) {
+	if name == "-" {
+		return stdin, func() {}, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { f.Close() }, nil
+}
+
+// writeOutput runs write on stdout for "-", otherwise on a temporary
+// file that replaces the named file only if write succeeds.
+func writeOutput(name string, stdout io.Writer, write func(io.Writer) error) error {
+	if name == "-" {
+		return write(stdout)
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // fails harmlessly after the rename
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
@@ -0,0 +1,162 @@
+/*
+	main_test.go:  Unit tests of the serpent command-line tool.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runTest runs the tool and returns the exit code, stdout and stderr.
+func runTest(stdin []byte, args ...string) (int, []byte, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, bytes.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.Bytes(), stderr.String()
+}
+
+func TestKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	plainFile := filepath.Join(dir, "plain")
+	sealedFile := filepath.Join(dir, "sealed")
+	openedFile := filepath.Join(dir, "opened")
+	content := bytes.Repeat([]byte("serpent "), 20000)
+	os.WriteFile(plainFile, content, 0600)
+
+	if code, _, stderr := runTest(nil, "keygen", "-out", keyFile); code != exitOK {
+		t.Fatalf("keygen: %d %s", code, stderr)
+	}
+	if code, _, stderr := runTest(nil, "encrypt", "-key-file", keyFile, "-in", plainFile, "-out", sealedFile); code != exitOK {
+		t.Fatalf("encrypt: %d %s", code, stderr)
+	}
+	if code, _, stderr := runTest(nil, "decrypt", "-key-file", keyFile, "-in", sealedFile, "-out", openedFile); code != exitOK {
+		t.Fatalf("decrypt: %d %s", code, stderr)
+	}
+	if opened, _ := os.ReadFile(openedFile); !bytes.Equal(opened, content) {
+		t.Errorf("Decrypted file differs")
+	}
+
+	code, stdout, _ := runTest(nil, "inspect", "-in", sealedFile)
+	if code != exitOK || !strings.Contains(string(stdout), "KDF none") {
+		t.Errorf("inspect: %d %s", code, stdout)
+	}
+
+	// Another key.
+	otherKey := filepath.Join(dir, "other")
+	runTest(nil, "keygen", "-out", otherKey)
+	code, _, stderr := runTest(nil, "decrypt", "-key-file", otherKey, "-in", sealedFile, "-out", filepath.Join(dir, "x"))
+	if code != exitWrongKey || !strings.Contains(stderr, "wrong key") {
+		t.Errorf("Wrong key. Is %d %q, should: %d", code, stderr, exitWrongKey)
+	}
+
+	// Damaged content, the output file must not appear.
+	sealed, _ := os.ReadFile(sealedFile)
+	sealed[len(sealed)/2] ^= 1
+	os.WriteFile(sealedFile, sealed, 0600)
+	code, _, stderr = runTest(nil, "decrypt", "-key-file", keyFile, "-in", sealedFile, "-out", filepath.Join(dir, "damaged"))
+	if code != exitCorrupt || !strings.Contains(stderr, "corrupted") {
+		t.Errorf("Damaged content. Is %d %q, should: %d", code, stderr, exitCorrupt)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "damaged")); !os.IsNotExist(err) {
+		t.Errorf("Output of damaged content was written")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 5 {
+		t.Errorf("Temporary files left: %d entries", len(entries))
+	}
+}
+
+func TestPassphrasePipe(t *testing.T) {
+	dir := t.TempDir()
+	passFile := filepath.Join(dir, "pass")
+	os.WriteFile(passFile, []byte("correct horse battery staple\n"), 0600)
+	content := []byte("piped through stdin and stdout")
+
+	code, sealed, stderr := runTest(content, "encrypt", "-passphrase-file", passFile, "-iterations", "1000", "-mode", "ocb")
+	if code != exitOK {
+		t.Fatalf("encrypt: %d %s", code, stderr)
+	}
+	code, opened, stderr := runTest(sealed, "decrypt", "-passphrase-file", passFile)
+	if code != exitOK || !bytes.Equal(opened, content) {
+		t.Errorf("decrypt: %d %q %s", code, opened, stderr)
+	}
+
+	code, info, _ := runTest(sealed, "inspect")
+	if code != exitOK || !strings.Contains(string(info), ", OCB, ") ||
+		!strings.Contains(string(info), "1000 iterations") {
+		t.Errorf("inspect: %d %s", code, info)
+	}
+
+	t.Setenv(passphraseEnv, "wrong")
+	if code, _, _ := runTest(sealed, "decrypt"); code != exitWrongKey {
+		t.Errorf("Wrong passphrase. Exit code is %d, should: %d", code, exitWrongKey)
+	}
+
+	// A forged header with a huge iteration count and a recomputed CRC
+	// is refused before any key is derived.
+	forged := append([]byte(nil), sealed...)
+	binary.BigEndian.PutUint32(forged[12:], 0xffffffff)
+	saltEnd := 21 + int(forged[20])
+	crcAt := saltEnd + 1 + int(forged[saltEnd]) + 16
+	binary.BigEndian.PutUint32(forged[crcAt:], crc32.ChecksumIEEE(forged[:crcAt]))
+	if code, _, stderr := runTest(forged, "decrypt", "-passphrase-file", passFile); code != exitFormat {
+		t.Errorf("Huge iteration count. Is %d %q, should: %d", code, stderr, exitFormat)
+	}
+
+	sealed[12] ^= 1 // iterations field, caught by the header CRC
+	if code, _, stderr := runTest(sealed, "decrypt", "-passphrase-file", passFile); code != exitCorrupt {
+		t.Errorf("Damaged header. Is %d %q, should: %d", code, stderr, exitCorrupt)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	runTest(nil, "keygen", "-out", keyFile)
+
+	tests := []struct {
+		stdin []byte
+		args  []string
+		code  int
+	}{
+		{nil, nil, exitUsage},
+		{nil, []string{"frobnicate"}, exitUsage},
+		{nil, []string{"encrypt", "-bogus"}, exitUsage},
+		{nil, []string{"encrypt", "-key-file", keyFile, "-mode", "ecb"}, exitUsage},
+		{nil, []string{"encrypt", "-key-file", keyFile, "-passphrase-file", keyFile}, exitUsage},
+		{nil, []string{"encrypt", "-key-file", keyFile, "-iterations", "16777217"}, exitUsage},
+		{nil, []string{"decrypt", "-key-file", keyFile, "extra"}, exitUsage},
+		{nil, []string{"decrypt", "-key-file", filepath.Join(dir, "missing")}, exitIO},
+		{nil, []string{"decrypt", "-key-file", keyFile, "-in", filepath.Join(dir, "missing")}, exitIO},
+		{nil, []string{"encrypt", "-key-file", keyFile, "-out", filepath.Join(dir, "no", "dir")}, exitIO},
+		{[]byte("plain text, not a container"), []string{"decrypt", "-key-file", keyFile}, exitFormat},
+		{[]byte("plain text, not a container"), []string{"inspect"}, exitFormat},
+	}
+	for _, tt := range tests {
+		if code, _, stderr := runTest(tt.stdin, tt.args...); code != tt.code {
+			t.Errorf("%v. Exit code is %d (%q), should: %d", tt.args, code, stderr, tt.code)
+		}
+	}
+}
@@ -0,0 +1,431 @@
+/*
+	container.go:  Serpent algorithm implementation in Go.
+
+	Encrypted container format: a versioned, authenticated header
+	followed by the STREAM segments of the content.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// The container header, all numbers big-endian:
+//
+//	magic        8  "SERPENT" 0x1a
+//	version      1  ContainerVersion
+//	algorithm    1  AlgSerpent256
+//	mode         1  ModeGCM, ModeOCB, ModeEAX or ModeCTRPoly1305
+//	kdf          1  KDFNone or KDFPBKDF2
+//	iterations   4  PBKDF2 iterations, 0 for KDFNone
+//	segment size 4  plaintext bytes per STREAM segment
+//	salt         1  length, then the salt
+//	nonce prefix 1  length, then the STREAM nonce prefix
+//	MAC         16  Serpent-CMAC of the fields above under the MAC key
+//	CRC          4  CRC-32 (IEEE) of everything above
+//
+// The secret (a passphrase for KDFPBKDF2, a key for KDFNone) gives
+// PBKDF2-HMAC-SHA256(secret, salt, iterations, 64 bytes), with one
+// iteration for KDFNone: the first 32 bytes are the content key, the
+// last 32 the header MAC key. The CRC tells a damaged header from
+// a wrong secret, which both fail the MAC.
+const (
+	ContainerVersion = 1
+
+	AlgSerpent256 = 1
+
+	ModeGCM         = 1
+	ModeOCB         = 2
+	ModeEAX         = 3
+	ModeCTRPoly1305 = 4
+
+	KDFNone   = 0
+	KDFPBKDF2 = 1
+
+	// DefaultIterations is the PBKDF2 iteration count used when
+	// zero is given.
+	DefaultIterations = 600000
+	// MaxIterations is the largest PBKDF2 iteration count accepted.
+	// The header is only authenticated after the keys are derived,
+	// so a larger count in a forged header would be a cheap way to
+	// make the reader burn CPU.
+	MaxIterations = 1 << 24
+
+	containerSaltSize = 16
+	containerMaxField = 64
+	containerFixed    = 20
+)
+
+var containerMagic = []byte("SERPENT\x1a")
+
+var (
+	// ErrContainerFormat is returned for input that is not a container
+	// of a known version, algorithm, mode or KDF.
+	ErrContainerFormat = errors.New("serpent: not a supported container")
+	// ErrContainerCorrupt is returned for a damaged container header.
+	ErrContainerCorrupt = errors.New("serpent: container header is corrupted")
+	// ErrWrongKey is returned when the header MAC does not verify
+	// under the given secret.
+	ErrWrongKey = errors.New("serpent: wrong key or passphrase")
+)
+
+// ContainerHeader describes an encrypted container.
+type ContainerHeader struct {
+	Version     uint8
+	Algorithm   uint8
+	Mode        uint8
+	KDF         uint8
+	Iterations  uint32
+	SegmentSize uint32
+	Salt        []byte
+	NoncePrefix []byte
+}
+
+// String returns a one-line description of the header.
+func (h *ContainerHeader) String() string {
+	kdf := "none"
+	if h.KDF == KDFPBKDF2 {
+		kdf = fmt.Sprintf("PBKDF2-HMAC-SHA256, %d iterations", h.Iterations)
+	}
+	return fmt.Sprintf("version %d, %s, %s, KDF %s, segment size %d, salt %x, nonce prefix %x",
+		h.Version, algorithmName(h.Algorithm), modeName(h.Mode), kdf, h.SegmentSize, h.Salt, h.NoncePrefix)
+}
+
+func algorithmName(alg uint8) string {
+	if alg == AlgSerpent256 {
+		return "Serpent-256"
+	}
+	return fmt.Sprintf("algorithm %d", alg)
+}
+
+// modeNames are the names of the container modes.
+var modeNames = [...]string{
+	ModeGCM:         "GCM",
+	ModeOCB:         "OCB",
+	ModeEAX:         "EAX",
+	ModeCTRPoly1305: "CTR-Poly1305",
+}
+
+func modeName(mode uint8) string {
+	if int(mode) < len(modeNames) && modeNames[mode] != "" {
+		return modeNames[mode]
+	}
+	return fmt.Sprintf("mode %d", mode)
+}
+
+// ParseMode returns the container mode with the given name, such as
+// "GCM" or "ctr-poly1305"; case is ignored.
+func ParseMode(name string) (uint8, bool) {
+	for mode, n := range modeNames {
+		if n != "" && strings.EqualFold(n, name) {
+			return uint8(mode), true
+		}
+	}
+	return 0, false
+}
+
+// newContainerAEAD returns the AEAD of a container mode.
+func newContainerAEAD(mode uint8, key []byte) (cipher.AEAD, error) {
+	b, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case ModeGCM:
+		return NewGCM(b)
+	case ModeOCB:
+		return NewOCB(b)
+	case ModeEAX:
+		return NewEAX(b)
+	case ModeCTRPoly1305:
+		return NewCTRPoly1305(b)
+	}
+	return nil, ErrContainerFormat
+}
+
+// EncodeContainer writes a header built from h to w and returns a
+// writer that encrypts the content into w; it must be closed. It also
+// returns the header written. h itself is not changed, so it can be
+// reused as a template. Zero fields of h get the defaults: GCM, a
+// segment size of DefaultSegmentSize and a fresh random salt and nonce
+// prefix on every call. The KDF is KDFNone, where the secret is a key of
+// at least 16 bytes, unless h.KDF is KDFPBKDF2, whose iterations default
+// to DefaultIterations. A salt or nonce prefix given in h must be unique
+// for every container under the same secret: repeating one repeats
+// the content key and the nonces.
+func EncodeContainer(w io.Writer, secret []byte, template *ContainerHeader) (io.WriteCloser, *ContainerHeader, error) {
+	h := *template
+	h.Version = ContainerVersion
+	h.Algorithm = AlgSerpent256
+	if h.Mode == 0 {
+		h.Mode = ModeGCM
+	}
+	if h.KDF == KDFPBKDF2 && h.Iterations == 0 {
+		h.Iterations = DefaultIterations
+	}
+	if h.KDF == KDFNone {
+		h.Iterations = 0
+	}
+	if h.SegmentSize == 0 {
+		h.SegmentSize = DefaultSegmentSize
+	}
+	if h.Salt == nil {
+		h.Salt = make([]byte, containerSaltSize)
+		if _, err := io.ReadFull(rand.Reader, h.Salt); err != nil {
+			return nil, nil, err
+		}
+	}
+	aead, err := newContainerAEAD(h.Mode, make([]byte, 32))
+	if err != nil {
+		return nil, nil, err
+	}
+	if h.NoncePrefix == nil {
+		h.NoncePrefix = make([]byte, aead.NonceSize()-streamNonceSuffix)
+		if _, err := io.ReadFull(rand.Reader, h.NoncePrefix); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := h.check(); err != nil {
+		return nil, nil, err
+	}
+
+	contentKey, macKey, err := h.deriveKeys(secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := h.marshal()
+	mac, err := headerMAC(macKey, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	header = append(header, mac...)
+	header = appendCRC(header)
+	if _, err := w.Write(header); err != nil {
+		return nil, nil, err
+	}
+
+	aead, _ = newContainerAEAD(h.Mode, contentKey)
+	sw, err := NewStreamWriter(w, aead, h.NoncePrefix, int(h.SegmentSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return sw, &h, nil
+}
+
+// DecodeContainer reads and verifies the header from r and returns
+// a reader of the decrypted content. A wrong secret gives ErrWrongKey,
+// damaged content gives ErrStreamAuth from the reader.
+func DecodeContainer(r io.Reader, secret []byte) (io.Reader, *ContainerHeader, error) {
+	h, header, mac, err := readContainerHeader(r)
+	if err != nil {
+		return nil, h, err
+	}
+	contentKey, macKey, err := h.deriveKeys(secret)
+	if err != nil {
+		return nil, h, err
+	}
+	expected, err := headerMAC(macKey, header)
+	if err != nil {
+		return nil, h, err
+	}
+	if subtle.ConstantTimeCompare(expected, mac) != 1 {
+		return nil, h, ErrWrongKey
+	}
+	aead, err := newContainerAEAD(h.Mode, contentKey)
+	if err != nil {
+		return nil, h, err
+	}
+	sr, err := NewStreamReader(r, aead, h.NoncePrefix, int(h.SegmentSize))
+	if err != nil {
+		return nil, h, err
+	}
+	return sr, h, nil
+}
+
+// InspectContainer reads the header from r and returns its parameters
+// without a key. The header MAC is not verified.
+func InspectContainer(r io.Reader) (*ContainerHeader, error) {
+	h, _, _, err := readContainerHeader(r)
+	return h, err
+}
+
+// readContainerHeader parses the header and checks its CRC. It returns
+// the header bytes covered by the MAC and the MAC.
+func readContainerHeader(r io.Reader) (*ContainerHeader, []byte, []byte, error) {
+	header := make([]byte, containerFixed+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrContainerFormat
+		}
+		return nil, nil, nil, err
+	}
+	if !bytes.Equal(header[:len(containerMagic)], containerMagic) {
+		return nil, nil, nil, ErrContainerFormat
+	}
+	// Read the variable fields, then the MAC and CRC.
+	var err error
+	if header, err = readMore(r, header, int(header[containerFixed])+1); err != nil {
+		return nil, nil, nil, err
+	}
+	if header, err = readMore(r, header, int(header[len(header)-1])+BlockSize+4); err != nil {
+		return nil, nil, nil, err
+	}
+	crc := binary.BigEndian.Uint32(header[len(header)-4:])
+	if crc32.ChecksumIEEE(header[:len(header)-4]) != crc {
+		return nil, nil, nil, ErrContainerCorrupt
+	}
+	header = header[:len(header)-4]
+	mac := header[len(header)-BlockSize:]
+	header = header[:len(header)-BlockSize]
+
+	h := &ContainerHeader{
+		Version:     header[8],
+		Algorithm:   header[9],
+		Mode:        header[10],
+		KDF:         header[11],
+		Iterations:  binary.BigEndian.Uint32(header[12:]),
+		SegmentSize: binary.BigEndian.Uint32(header[16:]),
+	}
+	saltEnd := containerFixed + 1 + int(header[containerFixed])
+	h.Salt = append([]byte(nil), header[containerFixed+1:saltEnd]...)
+	h.NoncePrefix = append([]byte(nil), header[saltEnd+1:]...)
+	if err := h.check(); err != nil {
+		return h, nil, nil, err
+	}
+	return h, header, mac, nil
+}
+
+func readMore(r io.Reader, b []byte, n int) ([]byte, error) {
+	b = append(b, make([]byte, n)...)
+	if _, err := io.ReadFull(r, b[len(b)-n:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrContainerCorrupt
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// check validates the header fields.
+func (h *ContainerHeader) check() error {
+	if h.Version != ContainerVersion || h.Algorithm != AlgSerpent256 {
+		return ErrContainerFormat
+	}
+	aead, err := newContainerAEAD(h.Mode, make([]byte, 32))
+	if err != nil {
+		return err
+	}
+	switch {
+	case h.KDF == KDFNone && h.Iterations != 0,
+		h.KDF == KDFPBKDF2 && (h.Iterations == 0 || h.Iterations > MaxIterations),
+		h.KDF > KDFPBKDF2:
+		return ErrContainerFormat
+	}
+	if len(h.Salt) < containerSaltSize || len(h.Salt) > containerMaxField ||
+		len(h.NoncePrefix) != aead.NonceSize()-streamNonceSuffix {
+		return ErrContainerFormat
+	}
+	if h.SegmentSize == 0 || h.SegmentSize > 1<<30 {
+		return ErrContainerFormat
+	}
+	return nil
+}
+
+func (h *ContainerHeader) marshal() []byte {
+	b := append([]byte(nil), containerMagic...)
+	b = append(b, h.Version, h.Algorithm, h.Mode, h.KDF)
+	b = append(b, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[12:], h.Iterations)
+	binary.BigEndian.PutUint32(b[16:], h.SegmentSize)
+	b = append(b, byte(len(h.Salt)))
+	b = append(b, h.Salt...)
+	b = append(b, byte(len(h.NoncePrefix)))
+	return append(b, h.NoncePrefix...)
+}
+
+// deriveKeys returns the content key and the header MAC key.
+func (h *ContainerHeader) deriveKeys(secret []byte) ([]byte, []byte, error) {
+	iterations := 1
+	if h.KDF == KDFNone {
+		if len(secret) < 16 {
+			return nil, nil, KeySizeError(len(secret))
+		}
+	} else {
+		iterations = int(h.Iterations)
+	}
+	keys := pbkdf2Key(secret, h.Salt, iterations, 64, sha256.New)
+	return keys[:32], keys[32:], nil
+}
+
+func headerMAC(key, header []byte) ([]byte, error) {
+	b, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	m, err := NewCMAC(b)
+	if err != nil {
+		return nil, err
+	}
+	m.Write(header)
+	return m.Sum(nil), nil
+}
+
+func appendCRC(b []byte) []byte {
+	var crc [4]byte
+	binary.BigEndian.PutUint32(crc[:], crc32.ChecksumIEEE(b))
+	return append(b, crc[:]...)
+}
+
+// pbkdf2Key derives a key of keyLen bytes (RFC 8018).
+func pbkdf2Key(password, salt []byte, iterations, keyLen int, h func() hash.Hash) []byte {
+	prf := hmac.New(h, password)
+	size := prf.Size()
+	var out []byte
+	var index [4]byte
+	u := make([]byte, size)
+	t := make([]byte, size)
+	for block := uint32(1); len(out) < keyLen; block++ {
+		binary.BigEndian.PutUint32(index[:], block)
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(index[:])
+		u = prf.Sum(u[:0])
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			xorBytes(t, t, u)
+		}
+		out = append(out, t...)
+	}
+	return out[:keyLen]
+}
@@ -0,0 +1,237 @@
+/*
+	container_test.go:  Unit tests of the encrypted container format.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+)
+
+// PBKDF2-HMAC-SHA256 test vectors (RFC 7914 section 11 and the
+// commonly used extension of RFC 6070).
+func TestPBKDF2(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		iterations     int
+		key            string
+	}{
+		{"password", "salt", 1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+			"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+	}
+	for _, tt := range tests {
+		expected := mustHex(t, tt.key)
+		key := pbkdf2Key([]byte(tt.password), []byte(tt.salt), tt.iterations, len(expected), sha256.New)
+		if !bytes.Equal(key, expected) {
+			t.Errorf("%s/%s/%d. Is %x, should: %x", tt.password, tt.salt, tt.iterations, key, expected)
+		}
+	}
+}
+
+func encodeTestContainer(t *testing.T, secret, content []byte, h *ContainerHeader) ([]byte, *ContainerHeader) {
+	var out bytes.Buffer
+	w, used, err := EncodeContainer(&out, secret, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes(), used
+}
+
+func decodeTestContainer(secret, container []byte) ([]byte, error) {
+	r, _, err := DecodeContainer(bytes.NewReader(container), secret)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestContainer(t *testing.T) {
+	rawKey, _ := testKeyAndIV()
+	passphrase := []byte("correct horse battery staple")
+	content := testMessage(1000)
+
+	for _, mode := range []uint8{ModeGCM, ModeOCB, ModeEAX, ModeCTRPoly1305} {
+		for _, kdf := range []uint8{KDFNone, KDFPBKDF2} {
+			secret := rawKey
+			if kdf == KDFPBKDF2 {
+				secret = passphrase
+			}
+			h := &ContainerHeader{Mode: mode, KDF: kdf, Iterations: 10, SegmentSize: 100}
+			container, h := encodeTestContainer(t, secret, content, h)
+
+			decoded, err := decodeTestContainer(secret, container)
+			if err != nil || !bytes.Equal(decoded, content) {
+				t.Errorf("%s: Is (%x, %v), should: %x", h, decoded, err, content)
+			}
+
+			inspected, err := InspectContainer(bytes.NewReader(container))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if inspected.String() != h.String() {
+				t.Errorf("Inspected header. Is %s, should: %s", inspected, h)
+			}
+		}
+	}
+}
+
+// A header reused as a template must not give two containers
+// the same key and nonces.
+func TestContainerTemplateReuse(t *testing.T) {
+	rawKey, _ := testKeyAndIV()
+	template := &ContainerHeader{KDF: KDFNone}
+	first, h1 := encodeTestContainer(t, rawKey, testMessage(10), template)
+	second, h2 := encodeTestContainer(t, rawKey, testMessage(10), template)
+	if template.Salt != nil || template.NoncePrefix != nil || template.Mode != 0 {
+		t.Errorf("Template changed: %s", template)
+	}
+	if bytes.Equal(h1.Salt, h2.Salt) || bytes.Equal(h1.NoncePrefix, h2.NoncePrefix) {
+		t.Errorf("Salt or nonce prefix repeated: %s, %s", h1, h2)
+	}
+	if bytes.Equal(first, second) {
+		t.Errorf("Containers are equal")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for mode := uint8(ModeGCM); mode <= ModeCTRPoly1305; mode++ {
+		for _, name := range []string{modeName(mode), strings.ToLower(modeName(mode))} {
+			if parsed, ok := ParseMode(name); !ok || parsed != mode {
+				t.Errorf("%q. Is (%d, %v), should: (%d, true)", name, parsed, ok, mode)
+			}
+		}
+	}
+	for _, name := range []string{"", "ecb", "mode 9"} {
+		if _, ok := ParseMode(name); ok {
+			t.Errorf("%q should not parse", name)
+		}
+	}
+}
+
+func TestContainerHeaderLayout(t *testing.T) {
+	h := &ContainerHeader{
+		Version:     ContainerVersion,
+		Algorithm:   AlgSerpent256,
+		Mode:        ModeGCM,
+		KDF:         KDFPBKDF2,
+		Iterations:  0x01020304,
+		SegmentSize: 0x10000,
+		Salt:        []byte{0xaa, 0xbb},
+		NoncePrefix: []byte{1, 2, 3, 4, 5, 6, 7},
+	}
+	expected := mustHex(t, "53455250454e541a"+"01010101"+"01020304"+"00010000"+
+		"02aabb"+"0701020304050607")
+	if b := h.marshal(); !bytes.Equal(b, expected) {
+		t.Errorf("Is %x, should: %x", b, expected)
+	}
+}
+
+func TestContainerErrors(t *testing.T) {
+	rawKey, _ := testKeyAndIV()
+	content := testMessage(300)
+	container, h := encodeTestContainer(t, rawKey, content, &ContainerHeader{KDF: KDFNone, SegmentSize: 64})
+	headerSize := containerFixed + 2 + len(h.Salt) + len(h.NoncePrefix) + BlockSize + 4
+
+	otherKey := append([]byte(nil), rawKey...)
+	otherKey[0] ^= 1
+	if _, err := decodeTestContainer(otherKey, container); err != ErrWrongKey {
+		t.Errorf("Wrong key. Error is %v, should: %v", err, ErrWrongKey)
+	}
+
+	// Damage anywhere in the header is found by the CRC,
+	// damage in the content by the STREAM segments.
+	for _, i := range []int{9, 14, containerFixed + 3, headerSize - 10, headerSize - 1} {
+		damaged := append([]byte(nil), container...)
+		damaged[i] ^= 0x20
+		if _, err := decodeTestContainer(rawKey, damaged); err != ErrContainerCorrupt {
+			t.Errorf("Header byte %d damaged. Error is %v, should: %v", i, err, ErrContainerCorrupt)
+		}
+	}
+	damaged := append([]byte(nil), container...)
+	damaged[headerSize+100] ^= 1
+	if _, err := decodeTestContainer(rawKey, damaged); err != ErrStreamAuth {
+		t.Errorf("Content damaged. Error is %v, should: %v", err, ErrStreamAuth)
+	}
+	if _, err := decodeTestContainer(rawKey, container[:len(container)-1]); err != ErrStreamAuth {
+		t.Errorf("Content truncated. Error is %v, should: %v", err, ErrStreamAuth)
+	}
+	if _, err := decodeTestContainer(rawKey, container[:headerSize-3]); err != ErrContainerCorrupt {
+		t.Errorf("Header truncated. Error is %v, should: %v", err, ErrContainerCorrupt)
+	}
+
+	for _, data := range [][]byte{nil, []byte("not a container at all"), container[1:]} {
+		if _, err := InspectContainer(bytes.NewReader(data)); err != ErrContainerFormat {
+			t.Errorf("%q. Error is %v, should: %v", data, err, ErrContainerFormat)
+		}
+	}
+
+	// A header with a valid CRC but an unknown version is reported.
+	future := append([]byte(nil), container[:headerSize-4]...)
+	future[8] = 2
+	future = appendCRC(future)
+	inspected, err := InspectContainer(bytes.NewReader(future))
+	if err != ErrContainerFormat || inspected == nil || inspected.Version != 2 {
+		t.Errorf("Future version. Is (%v, %v), should: version 2, %v", inspected, err, ErrContainerFormat)
+	}
+
+	// Iterations and salt are checked before any key derivation: a forged
+	// header with a good CRC must not make the reader run PBKDF2 for long.
+	slow := append([]byte(nil), container[:headerSize-4]...)
+	slow[11] = KDFPBKDF2
+	binary.BigEndian.PutUint32(slow[12:], 0xffffffff)
+	slow = appendCRC(slow)
+	if _, err := decodeTestContainer([]byte("passphrase"), slow); err != ErrContainerFormat {
+		t.Errorf("Huge iteration count. Error is %v, should: %v", err, ErrContainerFormat)
+	}
+	unsalted := *h
+	unsalted.Salt = nil
+	forged := append(unsalted.marshal(), make([]byte, BlockSize)...)
+	forged = appendCRC(forged)
+	if _, err := decodeTestContainer(rawKey, forged); err != ErrContainerFormat {
+		t.Errorf("Empty salt. Error is %v, should: %v", err, ErrContainerFormat)
+	}
+	if _, _, err := EncodeContainer(io.Discard, rawKey, &ContainerHeader{Salt: []byte{}}); err != ErrContainerFormat {
+		t.Errorf("Encode with empty salt. Error is %v, should: %v", err, ErrContainerFormat)
+	}
+	tooSlow := &ContainerHeader{KDF: KDFPBKDF2, Iterations: MaxIterations + 1}
+	if _, _, err := EncodeContainer(io.Discard, rawKey, tooSlow); err != ErrContainerFormat {
+		t.Errorf("Encode with %d iterations. Error is %v, should: %v", tooSlow.Iterations, err, ErrContainerFormat)
+	}
+
+	if _, _, err := EncodeContainer(io.Discard, rawKey[:8], &ContainerHeader{}); err != KeySizeError(8) {
+		t.Errorf("Short key. Error is %v, should: %v", err, KeySizeError(8))
+	}
+	if _, _, err := EncodeContainer(io.Discard, rawKey, &ContainerHeader{Mode: 9}); err != ErrContainerFormat {
+		t.Errorf("Unknown mode. Error is %v, should: %v", err, ErrContainerFormat)
+	}
+}
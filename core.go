@@ -21,6 +21,10 @@
 */
 package serpent
 
+// MakeKey sets up the key from keyMaterial given as a hex string,
+// as in the C reference API. The hex string is a big-endian number,
+// so its last 8 digits become the first key word.
+// It is a convenience wrapper; MakeKeyBytes takes the raw key.
 func MakeKey(key *keyInstance, keyLen int, keyMaterial []byte) int {
 	if (keyLen % BITS_PER_WORD) > 0 {
 		return BAD_KEY_MAT
@@ -33,20 +37,102 @@ func MakeKey(key *keyInstance, keyLen int, keyMaterial []byte) int {
 	if (keyMaterial != nil) && (len(keyMaterial) > 0) {
 		key.keyMaterial = keyMaterial
 	}
+	clearWords(key.userKey)
 	if stringToWords(key.keyMaterial, key.userKey, WORDS_PER_KEY) != OK {
 		return BAD_KEY_MAT
 	}
-	if keyLen < BITS_PER_KEY {
-		shortToLongKey(key.userKey, keyLen)
-	}
-	makeSubkeys(key.userKey, key.KHat)
+	scheduleKey(key)
+	key.kHat()
 	return OK
 }
 
+// MakeKeyBytes sets up the key from raw key bytes.
+// The key length in bits is taken from the slice: 16 to 32 bytes
+// in steps of 4 bytes. Key bytes are read as little-endian words,
+// byte 0 is the least significant byte of the first key word.
+// This is the byte order of NewCipher and of the NESSIE test vectors,
+// and it is the reverse of the hex string passed to MakeKey.
+func MakeKeyBytes(key *keyInstance, keyMaterial []byte) error {
+	n := len(keyMaterial)
+	keyLen := n * BITS_PER_BYTE
+	if (n%BYTES_PER_WORD) > 0 || keyLen > BITS_PER_KEY || keyLen < BITS_PER_SHORTEST_KEY {
+		return KeySizeError(n)
+	}
+	key.keyLen = keyLen
+
+	clearWords(key.userKey)
+	for i := 0; i < n/BYTES_PER_WORD; i++ {
+		key.userKey[i] = uint(bytesToUint32(keyMaterial[i*BYTES_PER_WORD:]))
+	}
+	scheduleKey(key)
+	return nil
+}
+
+// NewKey returns a key instance set up from raw key bytes (see MakeKeyBytes).
+func NewKey(keyMaterial []byte) (*keyInstance, error) {
+	key := NewKeyInstance()
+	if err := MakeKeyBytes(key, keyMaterial); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// scheduleKey computes the subkeys of the fast backend. The subkeys
+// of the reference code (KHat) are only worked out by kHat, when the
+// reference BlockEncrypt or BlockDecrypt needs them.
+func scheduleKey(key *keyInstance) {
+	if key.keyLen < BITS_PER_KEY {
+		shortToLongKey(key.userKey, key.keyLen)
+	}
+	makeFastSubkeys(key.userKey, &key.subkeys)
+	key.KHat = nil
+}
+
+// kHat returns the key schedule of the reference code, derived from
+// the fast backend subkeys by the initial permutation on first use.
+func (key *keyInstance) kHat() [][]uint {
+	if key.KHat == nil {
+		K := newKeySchedule()
+		key.KHat = newKeySchedule()
+		for i := 0; i < (r + 1); i++ {
+			for j := 0; j < WORDS_PER_BLOCK; j++ {
+				K[i][j] = uint(key.subkeys[i*WORDS_PER_BLOCK+j])
+			}
+			IP(K[i], key.KHat[i])
+		}
+	}
+	return key.KHat
+}
+
+func clearWords(w []uint) {
+	for i := range w {
+		w[i] = 0
+	}
+}
+
 func BlockEncrypt(key *keyInstance, input, output []uint) {
-	encryptGivenKHat(input, key.KHat, output)
+	encryptGivenKHat(input, key.kHat(), output)
 }
 
 func BlockDecrypt(key *keyInstance, input, output []uint) {
-	decryptGivenKHat(input, key.KHat, output)
+	decryptGivenKHat(input, key.kHat(), output)
+}
+
+// encryptBlocks encrypts len(src)/BYTES_PER_BLOCK consecutive blocks
+// with the fast backend. dst and src may overlap exactly.
+func encryptBlocks(key *keyInstance, dst, src []byte) {
+	for i := 0; i+BYTES_PER_BLOCK <= len(src); i += BYTES_PER_BLOCK {
+		x := loadBlock(src[i:])
+		encryptBitslice(&key.subkeys, &x)
+		storeBlock(dst[i:], &x)
+	}
+}
+
+// decryptBlocks is the inverse of encryptBlocks.
+func decryptBlocks(key *keyInstance, dst, src []byte) {
+	for i := 0; i+BYTES_PER_BLOCK <= len(src); i += BYTES_PER_BLOCK {
+		x := loadBlock(src[i:])
+		decryptBitslice(&key.subkeys, &x)
+		storeBlock(dst[i:], &x)
+	}
 }
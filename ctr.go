@@ -0,0 +1,126 @@
+/*
+	ctr.go:  Serpent algorithm implementation in Go.
+
+	Counter mode with random access to the keystream.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ctrBatch is the number of counter blocks encrypted per call.
+const ctrBatch = 8
+
+// CTR is a counter mode keystream. It implements cipher.Stream and io.Seeker.
+//
+// The counter is the whole 128-bit IV read as a big-endian number;
+// block i of the keystream is the encryption of IV + i modulo 2^128,
+// so the counter wraps around to zero after 0xff..ff, as in crypto/cipher.
+// Positions are byte offsets into the keystream.
+type CTR struct {
+	b   cipher.Block
+	iv  [BlockSize]byte
+	pos uint64
+}
+
+// NewCTR returns a counter mode stream for the block b (see NewCipher)
+// starting at the counter iv. The iv must have BlockSize bytes.
+func NewCTR(b cipher.Block, iv []byte) (*CTR, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, errors.New("serpent: CTR requires a 128-bit block cipher")
+	}
+	if len(iv) != BlockSize {
+		return nil, Error(BAD_IV)
+	}
+	x := &CTR{b: b}
+	copy(x.iv[:], iv)
+	return x, nil
+}
+
+// XORKeyStream xors src with the keystream at the current position
+// and advances the position by len(src).
+func (x *CTR) XORKeyStream(dst, src []byte) {
+	x.XORKeyStreamAt(dst, src, x.pos)
+	x.pos += uint64(len(src))
+}
+
+// XORKeyStreamAt xors src with the keystream starting at byte offset.
+// It does not change the position, so it may be called concurrently.
+func (x *CTR) XORKeyStreamAt(dst, src []byte, offset uint64) {
+	if len(dst) < len(src) {
+		panic("serpent: output smaller than input")
+	}
+	if offset+uint64(len(src)) < offset {
+		panic("serpent: CTR offset out of range")
+	}
+	var counters, stream [ctrBatch * BlockSize]byte
+
+	index := offset / BlockSize
+	skip := int(offset % BlockSize)
+	for len(src) > 0 {
+		n := (skip + len(src) + BlockSize - 1) / BlockSize
+		if n > ctrBatch {
+			n = ctrBatch
+		}
+		for i := 0; i < n; i++ {
+			x.counter(index+uint64(i), counters[i*BlockSize:])
+		}
+		encryptBlocksWith(x.b, stream[:n*BlockSize], counters[:n*BlockSize])
+
+		k := xorBytes(dst, src, stream[skip:n*BlockSize])
+		dst, src = dst[k:], src[k:]
+		index += uint64(n)
+		skip = 0
+	}
+}
+
+// Seek sets the position for the next XORKeyStream.
+// whence is io.SeekStart or io.SeekCurrent.
+func (x *CTR) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = int64(x.pos) + offset
+	default:
+		return int64(x.pos), errors.New("serpent: CTR seek: invalid whence")
+	}
+	if pos < 0 {
+		return int64(x.pos), errors.New("serpent: CTR seek: negative position")
+	}
+	x.pos = uint64(pos)
+	return pos, nil
+}
+
+// counter writes IV + index (modulo 2^128) to out.
+func (x *CTR) counter(index uint64, out []byte) {
+	hi := binary.BigEndian.Uint64(x.iv[:8])
+	lo := binary.BigEndian.Uint64(x.iv[8:])
+	sum := lo + index
+	if sum < lo {
+		hi++
+	}
+	binary.BigEndian.PutUint64(out, hi)
+	binary.BigEndian.PutUint64(out[8:], sum)
+}
@@ -0,0 +1,148 @@
+/*
+	ctr_test.go:  Unit tests of the counter mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"io"
+	"testing"
+)
+
+func stdlibCTR(block cipher.Block, iv []byte, n int) []byte {
+	out := make([]byte, n)
+	cipher.NewCTR(block, iv).XORKeyStream(out, out)
+	return out
+}
+
+func TestCTR(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+
+	for _, n := range []int{0, 1, 15, 16, 17, 127, 128, 129, 1000} {
+		plainText := testMessage(n)
+		expected := make([]byte, n)
+		cipher.NewCTR(block, iv).XORKeyStream(expected, plainText)
+
+		stream, err := NewCTR(block, iv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output := make([]byte, n)
+		stream.XORKeyStream(output, plainText)
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d bytes. Is %x, should: %x", n, output, expected)
+		}
+	}
+}
+
+func TestCTRChunks(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	expected := stdlibCTR(block, iv, 500)
+
+	stream, _ := NewCTR(block, iv)
+	output := make([]byte, 0, len(expected))
+	for _, n := range []int{3, 13, 16, 1, 200, 7, 260} {
+		chunk := make([]byte, n)
+		stream.XORKeyStream(chunk, chunk)
+		output = append(output, chunk...)
+	}
+	if !bytes.Equal(output, expected) {
+		t.Errorf("Is %x, should: %x", output, expected)
+	}
+}
+
+func TestCTRXORKeyStreamAt(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	expected := stdlibCTR(block, iv, 1000)
+
+	stream, _ := NewCTR(block, iv)
+	for _, r := range [][2]int{{0, 10}, {5, 30}, {16, 16}, {31, 500}, {999, 1}, {400, 600}} {
+		output := make([]byte, r[1])
+		stream.XORKeyStreamAt(output, output, uint64(r[0]))
+		if !bytes.Equal(output, expected[r[0]:r[0]+r[1]]) {
+			t.Errorf("Offset %d. Is %x, should: %x", r[0], output, expected[r[0]:r[0]+r[1]])
+		}
+	}
+
+	if pos, err := stream.Seek(123, io.SeekStart); pos != 123 || err != nil {
+		t.Fatalf("Seek. Is (%d, %v), should: (123, nil)", pos, err)
+	}
+	output := make([]byte, 50)
+	stream.XORKeyStream(output, output)
+	if pos, _ := stream.Seek(-73, io.SeekCurrent); pos != 100 {
+		t.Errorf("Seek. Is %d, should: 100", pos)
+	}
+	if !bytes.Equal(output, expected[123:173]) {
+		t.Errorf("After Seek. Is %x, should: %x", output, expected[123:173])
+	}
+	if _, err := stream.Seek(-1, io.SeekStart); err == nil {
+		t.Error("Seek to a negative position should fail")
+	}
+	if _, err := stream.Seek(0, io.SeekEnd); err == nil {
+		t.Error("Seek from the end should fail")
+	}
+}
+
+// The counter is 128 bits wide and wraps around to zero.
+func TestCTRCounterWrap(t *testing.T) {
+	rawKey, _ := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	iv := bytes.Repeat([]byte{0xff}, BlockSize)
+	iv[BlockSize-1] = 0xfe
+	expected := stdlibCTR(block, iv, 4*BlockSize)
+
+	stream, _ := NewCTR(block, iv)
+	output := make([]byte, len(expected))
+	stream.XORKeyStream(output, output)
+	if !bytes.Equal(output, expected) {
+		t.Errorf("Is %x, should: %x", output, expected)
+	}
+
+	zero := make([]byte, BlockSize)
+	block.Encrypt(zero, zero)
+	if !bytes.Equal(output[2*BlockSize:3*BlockSize], zero) {
+		t.Errorf("Counter after 0xff..ff. Is %x, should: %x", output[2*BlockSize:3*BlockSize], zero)
+	}
+
+	// carry from the low into the high 64 bits
+	iv = make([]byte, BlockSize)
+	for i := 8; i < BlockSize; i++ {
+		iv[i] = 0xff
+	}
+	expected = stdlibCTR(block, iv, 2*BlockSize)
+	stream, _ = NewCTR(block, iv)
+	output = make([]byte, len(expected))
+	stream.XORKeyStream(output, output)
+	if !bytes.Equal(output, expected) {
+		t.Errorf("Is %x, should: %x", output, expected)
+	}
+}
+
+func TestNewCTRBadIV(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	if _, err := NewCTR(block, iv[:12]); err != Error(BAD_IV) {
+		t.Errorf("Error is %v, should: %v", err, Error(BAD_IV))
+	}
+}
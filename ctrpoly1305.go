@@ -0,0 +1,125 @@
+/*
+	ctrpoly1305.go:  Serpent algorithm implementation in Go.
+
+	Encrypt-then-MAC AEAD of Serpent-CTR and Poly1305.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const ctrPoly1305NonceSize = 12
+
+type ctrPoly1305 struct {
+	b cipher.Block
+}
+
+// NewCTRPoly1305 returns an AEAD built like ChaCha20-Poly1305 (RFC 8439)
+// with Serpent-CTR in place of ChaCha20. The counter block is the 96-bit
+// nonce followed by a 32-bit big-endian counter. Counter blocks 0 and 1
+// give the one-time Poly1305 key r || s, so s is the Serpent encryption
+// of a nonce block as in Poly1305-Serpent. The message is encrypted from
+// counter 2 on and the tag is computed over the ciphertext.
+func NewCTRPoly1305(b cipher.Block) (cipher.AEAD, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, errors.New("serpent: CTR-Poly1305 requires a 128-bit block cipher")
+	}
+	return &ctrPoly1305{b: b}, nil
+}
+
+func (c *ctrPoly1305) NonceSize() int {
+	return ctrPoly1305NonceSize
+}
+
+func (c *ctrPoly1305) Overhead() int {
+	return Poly1305TagSize
+}
+
+func (c *ctrPoly1305) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != ctrPoly1305NonceSize {
+		panic("serpent: incorrect nonce length given to CTR-Poly1305")
+	}
+	if uint64(len(plaintext)) > ((1<<32)-2)*BlockSize {
+		panic("serpent: message too large for CTR-Poly1305")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+Poly1305TagSize)
+
+	stream, mac := c.init(nonce)
+	stream.XORKeyStream(out, plaintext)
+
+	var tag [Poly1305TagSize]byte
+	c.auth(mac, &tag, out[:len(plaintext)], additionalData)
+	copy(out[len(plaintext):], tag[:])
+	return ret
+}
+
+func (c *ctrPoly1305) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != ctrPoly1305NonceSize {
+		panic("serpent: incorrect nonce length given to CTR-Poly1305")
+	}
+	if len(ciphertext) < Poly1305TagSize {
+		return nil, errOpen
+	}
+	if uint64(len(ciphertext)) > ((1<<32)-2)*BlockSize+Poly1305TagSize {
+		return nil, errOpen
+	}
+	tag := ciphertext[len(ciphertext)-Poly1305TagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-Poly1305TagSize]
+
+	stream, mac := c.init(nonce)
+	var expectedTag [Poly1305TagSize]byte
+	c.auth(mac, &expectedTag, ciphertext, additionalData)
+	if subtle.ConstantTimeCompare(expectedTag[:], tag) != 1 {
+		return nil, errOpen
+	}
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	stream.XORKeyStream(out, ciphertext)
+	return ret, nil
+}
+
+// init returns the keystream positioned at counter 2 and the Poly1305
+// state keyed with the first two keystream blocks.
+func (c *ctrPoly1305) init(nonce []byte) (*CTR, *poly1305) {
+	var iv [BlockSize]byte
+	copy(iv[:], nonce)
+	stream, _ := NewCTR(c.b, iv[:])
+
+	var key [Poly1305KeySize]byte
+	stream.XORKeyStream(key[:], key[:])
+	return stream, newPoly1305(key[:16], key[16:])
+}
+
+// auth computes the tag over additionalData and ciphertext,
+// each padded to 16 bytes, followed by their little-endian lengths.
+func (c *ctrPoly1305) auth(mac *poly1305, tag *[Poly1305TagSize]byte, ciphertext, additionalData []byte) {
+	mac.Write(additionalData)
+	mac.pad()
+	mac.Write(ciphertext)
+	mac.pad()
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(additionalData)))
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(ciphertext)))
+	mac.Write(lengths[:])
+	mac.Sum(tag)
+}
@@ -0,0 +1,101 @@
+/*
+	ctrpoly1305_test.go:  Unit tests of the CTR-Poly1305 AEAD.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"testing"
+)
+
+// The construction rebuilt from crypto/cipher CTR and Poly1305Sum.
+func TestCTRPoly1305Construction(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	aead, err := NewCTRPoly1305(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := iv[:12]
+
+	for _, n := range []int{0, 1, 16, 33, 200} {
+		plainText := testMessage(n)
+		aad := testMessage(n/2 + 1)
+
+		counter := make([]byte, BlockSize)
+		copy(counter, nonce)
+		stream := make([]byte, 32+n)
+		cipher.NewCTR(block, counter).XORKeyStream(stream, stream)
+		cipherText := make([]byte, n)
+		xorBytes(cipherText, plainText, stream[32:])
+
+		var macData []byte
+		for _, part := range [][]byte{aad, cipherText} {
+			macData = append(macData, part...)
+			macData = append(macData, make([]byte, (16-len(part)%16)%16)...)
+		}
+		var lengths [16]byte
+		binary.LittleEndian.PutUint64(lengths[:], uint64(len(aad)))
+		binary.LittleEndian.PutUint64(lengths[8:], uint64(n))
+		macData = append(macData, lengths[:]...)
+
+		var key [Poly1305KeySize]byte
+		copy(key[:], stream)
+		var tag [Poly1305TagSize]byte
+		Poly1305Sum(&tag, macData, &key)
+
+		expected := append(cipherText, tag[:]...)
+		if sealed := aead.Seal(nil, nonce, plainText, aad); !bytes.Equal(sealed, expected) {
+			t.Errorf("%d bytes. Is %x, should: %x", n, sealed, expected)
+		}
+	}
+}
+
+func TestCTRPoly1305(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	aead, _ := NewCTRPoly1305(block)
+	nonce := iv[:aead.NonceSize()]
+
+	for _, n := range []int{0, 1, 15, 16, 17, 128, 129, 300} {
+		plainText := testMessage(n)
+		aad := testMessage(n / 3)
+		sealed := aead.Seal(nil, nonce, plainText, aad)
+		if len(sealed) != n+aead.Overhead() {
+			t.Fatalf("Sealed length. Is %d, should: %d", len(sealed), n+aead.Overhead())
+		}
+		opened, err := aead.Open(nil, nonce, sealed, aad)
+		if err != nil || !bytes.Equal(opened, plainText) {
+			t.Errorf("%d bytes. Is (%x, %v), should: %x", n, opened, err, plainText)
+		}
+		if _, err := aead.Open(nil, nonce, sealed, append(aad, 0)); err != errOpen {
+			t.Errorf("%d bytes. Other data. Error is %v, should: %v", n, err, errOpen)
+		}
+		sealed[0] ^= 0x80
+		if _, err := aead.Open(nil, nonce, sealed, aad); err != errOpen {
+			t.Errorf("%d bytes. Tampered message. Error is %v, should: %v", n, err, errOpen)
+		}
+	}
+	if _, err := aead.Open(nil, nonce, make([]byte, 15), nil); err != errOpen {
+		t.Errorf("Short message. Error is %v, should: %v", err, errOpen)
+	}
+}
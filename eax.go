@@ -0,0 +1,175 @@
+/*
+	eax.go:  Serpent algorithm implementation in Go.
+
+	EAX authenticated encryption (Bellare, Rogaway, Wagner).
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+const eaxNonceSize = 16
+
+type eax struct {
+	b         cipher.Block
+	nonceSize int
+	tagSize   int
+}
+
+// NewEAX returns the block b wrapped in EAX mode with a 128-bit nonce
+// and a 128-bit tag.
+func NewEAX(b cipher.Block) (cipher.AEAD, error) {
+	return NewEAXWithSizes(b, eaxNonceSize, BlockSize)
+}
+
+// NewEAXWithSizes returns EAX with nonces of nonceSize bytes (any positive
+// length) and tags of tagSize bytes (1 to 16).
+func NewEAXWithSizes(b cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if err := checkEAX(b, tagSize); err != nil {
+		return nil, err
+	}
+	if nonceSize < 1 {
+		return nil, errors.New("serpent: invalid EAX nonce size")
+	}
+	return &eax{b: b, nonceSize: nonceSize, tagSize: tagSize}, nil
+}
+
+func checkEAX(b cipher.Block, tagSize int) error {
+	if b.BlockSize() != BlockSize {
+		return errors.New("serpent: EAX requires a 128-bit block cipher")
+	}
+	if tagSize < 1 || tagSize > BlockSize {
+		return errors.New("serpent: invalid EAX tag size")
+	}
+	return nil
+}
+
+func (e *eax) NonceSize() int {
+	return e.nonceSize
+}
+
+func (e *eax) Overhead() int {
+	return e.tagSize
+}
+
+func (e *eax) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != e.nonceSize {
+		panic("serpent: incorrect nonce length given to EAX")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+e.tagSize)
+	s := newEAXStream(e.b, nonce, additionalData, e.tagSize, false)
+	s.XORKeyStream(out, plaintext)
+	copy(out[len(plaintext):], s.Tag())
+	return ret
+}
+
+func (e *eax) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != e.nonceSize {
+		panic("serpent: incorrect nonce length given to EAX")
+	}
+	if len(ciphertext) < e.tagSize {
+		return nil, errOpen
+	}
+	tag := ciphertext[len(ciphertext)-e.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-e.tagSize]
+
+	s := newEAXStream(e.b, nonce, additionalData, e.tagSize, true)
+	s.mac.Write(ciphertext)
+	if err := s.Verify(tag); err != nil {
+		return nil, err
+	}
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	s.ctr.XORKeyStream(out, ciphertext)
+	return ret, nil
+}
+
+// EAXStream is EAX over a message given in chunks. It implements
+// cipher.Stream: every XORKeyStream call encrypts (or decrypts)
+// the next chunk and adds the ciphertext to the MAC.
+//
+// A decrypting stream returns plaintext before the tag has been checked;
+// the caller must not use any of it until Verify succeeds.
+type EAXStream struct {
+	ctr     *CTR
+	mac     *CMAC
+	nh      [BlockSize]byte // N' ^ H'
+	tagSize int
+	decrypt bool
+}
+
+// NewEAXEncrypter returns a stream encrypting a message under nonce
+// and header; the tag is available from Tag when all chunks are done.
+func NewEAXEncrypter(b cipher.Block, nonce, header []byte, tagSize int) (*EAXStream, error) {
+	if err := checkEAX(b, tagSize); err != nil {
+		return nil, err
+	}
+	return newEAXStream(b, nonce, header, tagSize, false), nil
+}
+
+// NewEAXDecrypter returns a stream decrypting a message under nonce
+// and header; the received tag is checked by Verify at the end.
+func NewEAXDecrypter(b cipher.Block, nonce, header []byte, tagSize int) (*EAXStream, error) {
+	if err := checkEAX(b, tagSize); err != nil {
+		return nil, err
+	}
+	return newEAXStream(b, nonce, header, tagSize, true), nil
+}
+
+func newEAXStream(b cipher.Block, nonce, header []byte, tagSize int, decrypt bool) *EAXStream {
+	s := &EAXStream{mac: newCMAC(b), tagSize: tagSize, decrypt: decrypt}
+	n := s.mac.omac(0, nonce)
+	h := s.mac.omac(1, header)
+	xorBytes(s.nh[:], n[:], h[:])
+	s.ctr, _ = NewCTR(b, n[:])
+
+	var tweak [BlockSize]byte
+	tweak[BlockSize-1] = 2
+	s.mac.Reset()
+	s.mac.Write(tweak[:])
+	return s
+}
+
+func (s *EAXStream) XORKeyStream(dst, src []byte) {
+	if s.decrypt {
+		s.mac.Write(src)
+		s.ctr.XORKeyStream(dst, src)
+		return
+	}
+	s.ctr.XORKeyStream(dst, src)
+	s.mac.Write(dst[:len(src)])
+}
+
+// Tag returns the tag of the chunks processed so far.
+func (s *EAXStream) Tag() []byte {
+	tag := s.mac.Sum(nil)
+	xorBytes(tag, tag, s.nh[:])
+	return tag[:s.tagSize]
+}
+
+// Verify compares tag with the tag of the chunks processed so far
+// in constant time.
+func (s *EAXStream) Verify(tag []byte) error {
+	if subtle.ConstantTimeCompare(s.Tag(), tag) != 1 {
+		return errOpen
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+	eax_test.go:  Unit tests of the EAX mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+// Test vectors from the EAX paper (AES-128).
+func TestEAXWithAES(t *testing.T) {
+	tests := []struct {
+		key, nonce, header, plainText, cipherText string
+	}{
+		{
+			"233952dee4d5ed5f9b9c6d6ff80ff478", "62ec67f9c3a4a407fcb2a8c49031a8b3", "6bfb914fd07eae6b",
+			"",
+			"e037830e8389f27b025a2d6527e79d01",
+		},
+		{
+			"91945d3f4dcbee0bf45ef52255f095a4", "becaf043b0a23d843194ba972c66debd", "fa3bfd4806eb53fa",
+			"f7fb",
+			"19dd5c4c9331049d0bdab0277408f67967e5",
+		},
+		{
+			"01f74ad64077f2e704c0f60ada3dd523", "70c3db4f0d26368400a10ed05d2bff5e", "234a3463c1264ac6",
+			"1a47cb4933",
+			"d851d5bae03a59f238a23e39199dc9266626c40f80",
+		},
+		{
+			"d07cf6cbb7f313bdde66b727afd3c5e8", "8408dfff3c1a2b1292dc199e46b7d617", "33cce2eabff5a79d",
+			"481c9e39b1",
+			"632a9d131ad4c168a4225d8e1ff755939974a7bede",
+		},
+	}
+	for i, tt := range tests {
+		block, _ := aes.NewCipher(mustHex(t, tt.key))
+		aead, err := NewEAX(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce, header, plainText := mustHex(t, tt.nonce), mustHex(t, tt.header), mustHex(t, tt.plainText)
+		expected := mustHex(t, tt.cipherText)
+
+		output := aead.Seal(nil, nonce, plainText, header)
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d: Seal. Is %x, should: %x", i, output, expected)
+		}
+		decrypted, err := aead.Open(nil, nonce, expected, header)
+		if err != nil || !bytes.Equal(decrypted, plainText) {
+			t.Errorf("%d: Open. Is (%x, %v), should: %x", i, decrypted, err, plainText)
+		}
+		expected[0] ^= 1
+		if _, err := aead.Open(nil, nonce, expected, header); err != errOpen {
+			t.Errorf("%d: Tampered message. Error is %v, should: %v", i, err, errOpen)
+		}
+	}
+}
+
+func TestEAX(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	header := []byte("device 17")
+
+	for _, sizes := range [][2]int{{16, 16}, {1, 16}, {12, 8}, {40, 4}} {
+		aead, err := NewEAXWithSizes(block, sizes[0], sizes[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce := testMessage(sizes[0])
+		for _, n := range []int{0, 1, 16, 33, 300} {
+			plainText := testMessage(n)
+			sealed := aead.Seal(nil, nonce, plainText, header)
+			opened, err := aead.Open(nil, nonce, sealed, header)
+			if err != nil || !bytes.Equal(opened, plainText) {
+				t.Errorf("Sizes %v, %d bytes. Is (%x, %v), should: %x", sizes, n, opened, err, plainText)
+			}
+			if _, err := aead.Open(nil, nonce, sealed, iv); err != errOpen {
+				t.Errorf("Sizes %v, %d bytes. Other header. Error is %v, should: %v", sizes, n, err, errOpen)
+			}
+		}
+	}
+}
+
+// Chunked processing gives the same result as Seal and Open.
+func TestEAXStream(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	aead, _ := NewEAX(block)
+	header := []byte("header")
+	plainText := testMessage(500)
+	expected := aead.Seal(nil, iv, plainText, header)
+
+	encrypter, err := NewEAXEncrypter(block, iv, header, BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output []byte
+	for _, chunk := range [][]byte{plainText[:7], plainText[7:16], plainText[16:17], plainText[17:300], plainText[300:]} {
+		out := make([]byte, len(chunk))
+		encrypter.XORKeyStream(out, chunk)
+		output = append(output, out...)
+	}
+	output = append(output, encrypter.Tag()...)
+	if !bytes.Equal(output, expected) {
+		t.Errorf("Encrypter. Is %x, should: %x", output, expected)
+	}
+
+	decrypter, _ := NewEAXDecrypter(block, iv, header, BlockSize)
+	cipherText := expected[:len(plainText)]
+	decrypted := make([]byte, len(cipherText))
+	for i := 0; i < len(cipherText); i += 64 {
+		end := i + 64
+		if end > len(cipherText) {
+			end = len(cipherText)
+		}
+		decrypter.XORKeyStream(decrypted[i:end], cipherText[i:end])
+	}
+	if err := decrypter.Verify(expected[len(plainText):]); err != nil {
+		t.Errorf("Decrypter. Verify: %v", err)
+	}
+	if !bytes.Equal(decrypted, plainText) {
+		t.Errorf("Decrypter. Is %x, should: %x", decrypted, plainText)
+	}
+	if err := decrypter.Verify(make([]byte, BlockSize)); err != errOpen {
+		t.Errorf("Decrypter. Bad tag. Error is %v, should: %v", err, errOpen)
+	}
+}
@@ -0,0 +1,180 @@
+/*
+	gcm.go:  Serpent algorithm implementation in Go.
+
+	Galois/Counter Mode (NIST SP 800-38D).
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	gcmStandardNonceSize = 12
+	gcmTagSize           = 16
+	gcmMinimumTagSize    = 12
+)
+
+var errOpen = errors.New("serpent: message authentication failed")
+
+type gcm struct {
+	b         cipher.Block
+	h         [BlockSize]byte
+	nonceSize int
+	tagSize   int
+}
+
+// NewGCM returns the block b (see NewCipher) wrapped in Galois/Counter Mode
+// with the standard 96-bit nonce and a 16-byte tag.
+func NewGCM(b cipher.Block) (cipher.AEAD, error) {
+	return newGCM(b, gcmStandardNonceSize, gcmTagSize)
+}
+
+// NewGCMWithTagSize is like NewGCM but produces tags of tagSize bytes,
+// from 12 to 16.
+func NewGCMWithTagSize(b cipher.Block, tagSize int) (cipher.AEAD, error) {
+	return newGCM(b, gcmStandardNonceSize, tagSize)
+}
+
+// NewGCMWithNonceSize is like NewGCM but takes nonces of size bytes.
+// Nonces other than 96 bits are hashed into the counter, use it only
+// for compatibility with existing data.
+func NewGCMWithNonceSize(b cipher.Block, size int) (cipher.AEAD, error) {
+	return newGCM(b, size, gcmTagSize)
+}
+
+func newGCM(b cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, errors.New("serpent: GCM requires a 128-bit block cipher")
+	}
+	if tagSize < gcmMinimumTagSize || tagSize > gcmTagSize {
+		return nil, errors.New("serpent: invalid GCM tag size")
+	}
+	if nonceSize <= 0 {
+		return nil, errors.New("serpent: invalid GCM nonce size")
+	}
+	g := &gcm{b: b, nonceSize: nonceSize, tagSize: tagSize}
+	b.Encrypt(g.h[:], g.h[:])
+	return g, nil
+}
+
+func (g *gcm) NonceSize() int {
+	return g.nonceSize
+}
+
+func (g *gcm) Overhead() int {
+	return g.tagSize
+}
+
+func (g *gcm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != g.nonceSize {
+		panic("serpent: incorrect nonce length given to GCM")
+	}
+	if uint64(len(plaintext)) > ((1<<32)-2)*BlockSize {
+		panic("serpent: message too large for GCM")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+g.tagSize)
+
+	var j0 [BlockSize]byte
+	g.deriveCounter(&j0, nonce)
+	g.counterCrypt(out, plaintext, &j0)
+
+	var tag [BlockSize]byte
+	g.auth(tag[:], out[:len(plaintext)], additionalData, &j0)
+	copy(out[len(plaintext):], tag[:])
+	return ret
+}
+
+func (g *gcm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != g.nonceSize {
+		panic("serpent: incorrect nonce length given to GCM")
+	}
+	if len(ciphertext) < g.tagSize {
+		return nil, errOpen
+	}
+	if uint64(len(ciphertext)) > ((1<<32)-2)*BlockSize+uint64(g.tagSize) {
+		return nil, errOpen
+	}
+	tag := ciphertext[len(ciphertext)-g.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-g.tagSize]
+
+	var j0 [BlockSize]byte
+	g.deriveCounter(&j0, nonce)
+
+	var expectedTag [BlockSize]byte
+	g.auth(expectedTag[:], ciphertext, additionalData, &j0)
+	if subtle.ConstantTimeCompare(expectedTag[:g.tagSize], tag) != 1 {
+		return nil, errOpen
+	}
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	g.counterCrypt(out, ciphertext, &j0)
+	return ret, nil
+}
+
+// deriveCounter computes the pre-counter block J0 for the nonce.
+func (g *gcm) deriveCounter(j0 *[BlockSize]byte, nonce []byte) {
+	if len(nonce) == gcmStandardNonceSize {
+		copy(j0[:], nonce)
+		j0[BlockSize-1] = 1
+		return
+	}
+	h := newGHASH(g.h[:])
+	h.update(nonce)
+	h.updateLengths(0, len(nonce))
+	h.sum(j0[:])
+}
+
+// counterCrypt xors in with the keystream starting at inc32(J0).
+// Only the last 32 bits of the counter are incremented.
+func (g *gcm) counterCrypt(out, in []byte, j0 *[BlockSize]byte) {
+	var counters, stream [ctrBatch * BlockSize]byte
+	ctr := binary.BigEndian.Uint32(j0[BlockSize-4:]) + 1
+	for len(in) > 0 {
+		n := (len(in) + BlockSize - 1) / BlockSize
+		if n > ctrBatch {
+			n = ctrBatch
+		}
+		for i := 0; i < n; i++ {
+			c := counters[i*BlockSize : (i+1)*BlockSize]
+			copy(c, j0[:BlockSize-4])
+			binary.BigEndian.PutUint32(c[BlockSize-4:], ctr)
+			ctr++
+		}
+		encryptBlocksWith(g.b, stream[:n*BlockSize], counters[:n*BlockSize])
+		k := xorBytes(out, in, stream[:n*BlockSize])
+		out, in = out[k:], in[k:]
+	}
+}
+
+// auth computes the full 16-byte tag E(J0) ^ GHASH(A, C).
+func (g *gcm) auth(out, ciphertext, additionalData []byte, j0 *[BlockSize]byte) {
+	h := newGHASH(g.h[:])
+	h.update(additionalData)
+	h.update(ciphertext)
+	h.updateLengths(len(additionalData), len(ciphertext))
+	h.sum(out)
+
+	var mask [BlockSize]byte
+	g.b.Encrypt(mask[:], j0[:])
+	xorBytes(out, out, mask[:])
+}
@@ -0,0 +1,203 @@
+/*
+	gcm_test.go:  Unit tests of the Galois/Counter Mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	enc "encoding/hex"
+	"os"
+	"strings"
+	"testing"
+)
+
+// readVectors reads a file from testdata with one test vector per line:
+// hex fields separated by spaces, '-' for an empty field, '#' for comments.
+func readVectors(t *testing.T, name string) [][][]byte {
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var vectors [][][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var fields [][]byte
+		for _, field := range strings.Fields(line) {
+			if field == "-" {
+				fields = append(fields, []byte{})
+				continue
+			}
+			b, err := enc.DecodeString(field)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fields = append(fields, b)
+		}
+		vectors = append(vectors, fields)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return vectors
+}
+
+// testdata/gcm.txt was generated with libgcrypt
+// (GCRY_CIPHER_SERPENT*, GCRY_CIPHER_MODE_GCM).
+func TestGCMVectors(t *testing.T) {
+	for i, v := range readVectors(t, "gcm.txt") {
+		key, nonce, aad, plainText, expected := v[0], v[1], v[2], v[3], v[4]
+		block, err := NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tagSize := len(expected) - len(plainText)
+		var aead cipher.AEAD
+		if len(nonce) != gcmStandardNonceSize {
+			aead, err = NewGCMWithNonceSize(block, len(nonce))
+		} else {
+			aead, err = NewGCMWithTagSize(block, tagSize)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		output := aead.Seal(nil, nonce, plainText, aad)
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d: Seal. Is %x, should: %x", i, output, expected)
+		}
+		decrypted, err := aead.Open(nil, nonce, expected, aad)
+		if err != nil || !bytes.Equal(decrypted, plainText) {
+			t.Errorf("%d: Open. Is (%x, %v), should: %x", i, decrypted, err, plainText)
+		}
+	}
+}
+
+// GCM test cases 1-3 of the GCM specification (McGrew, Viega) with AES
+// check GHASH and the counter handling independently of Serpent.
+func TestGCMWithAES(t *testing.T) {
+	tests := []struct {
+		key, nonce, plainText, cipherText, tag string
+	}{
+		{
+			"00000000000000000000000000000000",
+			"000000000000000000000000",
+			"",
+			"",
+			"58e2fccefa7e3061367f1d57a4e7455a",
+		},
+		{
+			"00000000000000000000000000000000",
+			"000000000000000000000000",
+			"00000000000000000000000000000000",
+			"0388dace60b6a392f328c2b971b2fe78",
+			"ab6e47d42cec13bdf53a67b21257bddf",
+		},
+		{
+			"feffe9928665731c6d6a8f9467308308",
+			"cafebabefacedbaddecaf888",
+			"d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a72" +
+				"1c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b391aafd255",
+			"42831ec2217774244b7221b784d0d49ce3aa212f2c02a4e035c17e2329aca12e" +
+				"21d514b25466931c7d8f6a5aac84aa051ba30b396a0aac973d58e091473f5985",
+			"4d5c2af327cd64a62cf35abd2ba6fab4",
+		},
+	}
+	for i, tt := range tests {
+		block, _ := aes.NewCipher(mustHex(t, tt.key))
+		aead, err := NewGCM(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := mustHex(t, tt.cipherText+tt.tag)
+		output := aead.Seal(nil, mustHex(t, tt.nonce), mustHex(t, tt.plainText), nil)
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d: Is %x, should: %x", i, output, expected)
+		}
+	}
+}
+
+func TestGCMOpenTampered(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	aead, _ := NewGCM(block)
+	nonce := iv[:aead.NonceSize()]
+	aad := []byte("header")
+	sealed := aead.Seal(nil, nonce, testMessage(40), aad)
+
+	for i := range sealed {
+		tampered := append([]byte(nil), sealed...)
+		tampered[i] ^= 0x01
+		if _, err := aead.Open(nil, nonce, tampered, aad); err != errOpen {
+			t.Errorf("Byte %d changed. Error is %v, should: %v", i, err, errOpen)
+		}
+	}
+	if _, err := aead.Open(nil, nonce, sealed, []byte("Header")); err != errOpen {
+		t.Errorf("Other additional data. Error is %v, should: %v", err, errOpen)
+	}
+	if _, err := aead.Open(nil, nonce, sealed[:15], aad); err != errOpen {
+		t.Errorf("Short input. Error is %v, should: %v", err, errOpen)
+	}
+}
+
+// Seal and Open append to dst and work in place.
+func TestGCMInPlace(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	aead, _ := NewGCM(block)
+	nonce := iv[:aead.NonceSize()]
+	plainText := testMessage(100)
+
+	buf := make([]byte, len(plainText), len(plainText)+aead.Overhead())
+	copy(buf, plainText)
+	sealed := aead.Seal(buf[:0], nonce, buf, nil)
+	opened, err := aead.Open(sealed[:0], nonce, sealed, nil)
+	if err != nil || !bytes.Equal(opened, plainText) {
+		t.Errorf("Is (%x, %v), should: %x", opened, err, plainText)
+	}
+
+	prefix := []byte("prefix")
+	out := aead.Seal(prefix, nonce, plainText, nil)
+	if !bytes.HasPrefix(out, prefix) || len(out) != len(prefix)+len(plainText)+aead.Overhead() {
+		t.Errorf("Seal does not append to dst")
+	}
+}
+
+func TestNewGCMErrors(t *testing.T) {
+	rawKey, _ := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	for _, tagSize := range []int{0, 4, 11, 17} {
+		if _, err := NewGCMWithTagSize(block, tagSize); err == nil {
+			t.Errorf("Tag size %d should be rejected", tagSize)
+		}
+	}
+	if _, err := NewGCMWithNonceSize(block, 0); err == nil {
+		t.Errorf("Nonce size 0 should be rejected")
+	}
+}
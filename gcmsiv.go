@@ -0,0 +1,169 @@
+/*
+	gcmsiv.go:  Serpent algorithm implementation in Go.
+
+	GCM-SIV (RFC 8452) with Serpent in place of AES. Every nonce gets
+	its own authentication and encryption keys, derived with the key
+	generating key, and the tag computed over POLYVAL is the CTR IV.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+const (
+	gcmSIVNonceSize = 12
+	gcmSIVTagSize   = 16
+	gcmSIVMaxLength = 1 << 36
+)
+
+type gcmSIV struct {
+	newCipher func([]byte) (cipher.Block, error)
+	kgk       cipher.Block // key generating key
+	keySize   int
+}
+
+// NewGCMSIV returns GCM-SIV for a 16 or 32 byte Serpent key
+// with 96-bit nonces and 128-bit tags.
+func NewGCMSIV(key []byte) (cipher.AEAD, error) {
+	return newGCMSIV(NewCipher, key)
+}
+
+func newGCMSIV(newCipher func([]byte) (cipher.Block, error), key []byte) (cipher.AEAD, error) {
+	if len(key) != 16 && len(key) != 32 {
+		return nil, KeySizeError(len(key))
+	}
+	kgk, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &gcmSIV{newCipher: newCipher, kgk: kgk, keySize: len(key)}, nil
+}
+
+func (g *gcmSIV) NonceSize() int {
+	return gcmSIVNonceSize
+}
+
+func (g *gcmSIV) Overhead() int {
+	return gcmSIVTagSize
+}
+
+func (g *gcmSIV) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("serpent: incorrect nonce length given to GCM-SIV")
+	}
+	if uint64(len(plaintext)) > gcmSIVMaxLength || uint64(len(additionalData)) > gcmSIVMaxLength {
+		panic("serpent: message too large for GCM-SIV")
+	}
+	authKey, enc := g.deriveKeys(nonce)
+	ret, out := sliceForAppend(dst, len(plaintext)+gcmSIVTagSize)
+
+	var tag [BlockSize]byte
+	g.tag(&tag, authKey, enc, nonce, plaintext, additionalData)
+	g.crypt(enc, out, plaintext, &tag)
+	copy(out[len(plaintext):], tag[:])
+	return ret
+}
+
+func (g *gcmSIV) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("serpent: incorrect nonce length given to GCM-SIV")
+	}
+	if len(ciphertext) < gcmSIVTagSize || uint64(len(ciphertext)) > gcmSIVMaxLength+gcmSIVTagSize ||
+		uint64(len(additionalData)) > gcmSIVMaxLength {
+		return nil, errOpen
+	}
+	var tag [BlockSize]byte
+	copy(tag[:], ciphertext[len(ciphertext)-gcmSIVTagSize:])
+	ciphertext = ciphertext[:len(ciphertext)-gcmSIVTagSize]
+
+	authKey, enc := g.deriveKeys(nonce)
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	g.crypt(enc, out, ciphertext, &tag)
+
+	var expectedTag [BlockSize]byte
+	g.tag(&expectedTag, authKey, enc, nonce, out, additionalData)
+	if subtle.ConstantTimeCompare(expectedTag[:], tag[:]) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// deriveKeys returns the message authentication key and a block keyed
+// with the message encryption key for the nonce. Each 8 bytes of key
+// are the first half of E(KGK, LE32(i) || nonce).
+func (g *gcmSIV) deriveKeys(nonce []byte) ([]byte, cipher.Block) {
+	key := make([]byte, BlockSize+g.keySize)
+	var in, out [BlockSize]byte
+	copy(in[4:], nonce)
+	for i := 0; i < len(key)/8; i++ {
+		binary.LittleEndian.PutUint32(in[:], uint32(i))
+		g.kgk.Encrypt(out[:], in[:])
+		copy(key[i*8:], out[:8])
+	}
+	enc, err := g.newCipher(key[BlockSize:])
+	if err != nil {
+		panic(err)
+	}
+	return key[:BlockSize], enc
+}
+
+func (g *gcmSIV) tag(tag *[BlockSize]byte, authKey []byte, enc cipher.Block, nonce, plaintext, additionalData []byte) {
+	p := newPOLYVAL(authKey)
+	p.update(additionalData)
+	p.update(plaintext)
+	var lengths [BlockSize]byte
+	binary.LittleEndian.PutUint64(lengths[:], uint64(len(additionalData))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext))*8)
+	p.update(lengths[:])
+	p.sum(tag[:])
+
+	xorBytes(tag[:], tag[:], nonce)
+	tag[BlockSize-1] &= 0x7f
+	enc.Encrypt(tag[:], tag[:])
+}
+
+// crypt runs CTR from the tag with its top bit set; the counter
+// is the first 32 bits, little-endian, modulo 2^32.
+func (g *gcmSIV) crypt(enc cipher.Block, out, in []byte, tag *[BlockSize]byte) {
+	var counters, stream [ctrBatch * BlockSize]byte
+	block := *tag
+	block[BlockSize-1] |= 0x80
+	ctr := binary.LittleEndian.Uint32(block[:4])
+	for len(in) > 0 {
+		n := (len(in) + BlockSize - 1) / BlockSize
+		if n > ctrBatch {
+			n = ctrBatch
+		}
+		for i := 0; i < n; i++ {
+			c := counters[i*BlockSize : (i+1)*BlockSize]
+			copy(c, block[:])
+			binary.LittleEndian.PutUint32(c, ctr)
+			ctr++
+		}
+		encryptBlocksWith(enc, stream[:n*BlockSize], counters[:n*BlockSize])
+		k := xorBytes(out, in, stream[:n*BlockSize])
+		out, in = out[k:], in[k:]
+	}
+}
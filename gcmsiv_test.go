@@ -0,0 +1,120 @@
+/*
+	gcmsiv_test.go:  Unit tests of POLYVAL and the GCM-SIV mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+)
+
+// Example of RFC 8452 appendix A.
+func TestPOLYVAL(t *testing.T) {
+	p := newPOLYVAL(mustHex(t, "25629347589242761d31f826ba4b757b"))
+	p.update(mustHex(t, "4f4f95668c83dfb6401762bb2d01a262"))
+	p.update(mustHex(t, "d1a24ddd2721d006bbe45f20d3c9f362"))
+	output := make([]byte, BlockSize)
+	p.sum(output)
+	expected := mustHex(t, "f7a3b47b846119fae5b7866cf5e5b77e")
+	if !bytes.Equal(output, expected) {
+		t.Errorf("Is %x, should: %x", output, expected)
+	}
+}
+
+func newAES(key []byte) (cipher.Block, error) {
+	return aes.NewCipher(key)
+}
+
+// Test vectors of RFC 8452 appendix C (AEAD_AES_128_GCM_SIV, AEAD_AES_256_GCM_SIV).
+func TestGCMSIVWithAES(t *testing.T) {
+	tests := []struct {
+		key, nonce, aad, plainText, result string
+	}{
+		{
+			"01000000000000000000000000000000", "030000000000000000000000",
+			"", "",
+			"dc20e2d83f25705bb49e439eca56de25",
+		},
+		{
+			"01000000000000000000000000000000", "030000000000000000000000",
+			"", "0100000000000000",
+			"b5d839330ac7b786578782fff6013b815b287c22493a364c",
+		},
+		{
+			"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000",
+			"", "",
+			"07f5f4169bbf55a8400cd47ea6fd400f",
+		},
+		{
+			"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000",
+			"", "0100000000000000",
+			"c2ef328e5c71c83b843122130f7364b761e0b97427e3df28",
+		},
+	}
+	for i, tt := range tests {
+		aead, err := newGCMSIV(newAES, mustHex(t, tt.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce, aad, plainText := mustHex(t, tt.nonce), mustHex(t, tt.aad), mustHex(t, tt.plainText)
+		expected := mustHex(t, tt.result)
+
+		output := aead.Seal(nil, nonce, plainText, aad)
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d: Seal. Is %x, should: %x", i, output, expected)
+		}
+		decrypted, err := aead.Open(nil, nonce, expected, aad)
+		if err != nil || !bytes.Equal(decrypted, plainText) {
+			t.Errorf("%d: Open. Is (%x, %v), should: %x", i, decrypted, err, plainText)
+		}
+	}
+}
+
+func TestGCMSIV(t *testing.T) {
+	_, iv := testKeyAndIV()
+	nonce := iv[:gcmSIVNonceSize]
+	for _, keySize := range []int{16, 32} {
+		aead, err := NewGCMSIV(testMessage(keySize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range []int{0, 1, 16, 17, 200} {
+			plainText := testMessage(n)
+			aad := []byte("additional data")
+			sealed := aead.Seal(nil, nonce, plainText, aad)
+			opened, err := aead.Open(nil, nonce, sealed, aad)
+			if err != nil || !bytes.Equal(opened, plainText) {
+				t.Errorf("Key %d, %d bytes. Is (%x, %v), should: %x", keySize, n, opened, err, plainText)
+			}
+			for _, i := range []int{0, len(sealed) - 1} {
+				sealed[i] ^= 0x40
+				if _, err := aead.Open(nil, nonce, sealed, aad); err != errOpen {
+					t.Errorf("Key %d, %d bytes. Byte %d changed. Error is %v, should: %v", keySize, n, i, err, errOpen)
+				}
+				sealed[i] ^= 0x40
+			}
+		}
+	}
+	if _, err := NewGCMSIV(make([]byte, 24)); err != KeySizeError(24) {
+		t.Errorf("Error is %v, should: %v", err, KeySizeError(24))
+	}
+}
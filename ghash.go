@@ -0,0 +1,97 @@
+/*
+	ghash.go:  Serpent algorithm implementation in Go.
+
+	GHASH, the universal hash of GCM (NIST SP 800-38D).
+	The multiplication runs over all 128 bits of the operand with masks
+	instead of branches or tables, so it takes the same time for any data.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import "encoding/binary"
+
+// fieldElement is an element of GF(2^128) in GCM bit order:
+// the most significant bit of hi is the coefficient of x^0.
+type fieldElement struct {
+	hi, lo uint64
+}
+
+func loadFieldElement(b []byte) fieldElement {
+	return fieldElement{binary.BigEndian.Uint64(b), binary.BigEndian.Uint64(b[8:])}
+}
+
+func (x fieldElement) store(b []byte) {
+	binary.BigEndian.PutUint64(b, x.hi)
+	binary.BigEndian.PutUint64(b[8:], x.lo)
+}
+
+// gfMul returns x*y in GF(2^128) modulo x^128 + x^7 + x^2 + x + 1.
+func gfMul(x, y fieldElement) fieldElement {
+	var z fieldElement
+	v := y
+	for i := 0; i < 128; i++ {
+		var bit uint64
+		if i < 64 {
+			bit = (x.hi >> uint(63-i)) & 1
+		} else {
+			bit = (x.lo >> uint(127-i)) & 1
+		}
+		mask := -bit
+		z.hi ^= v.hi & mask
+		z.lo ^= v.lo & mask
+
+		reduce := -(v.lo & 1)
+		v.lo = (v.lo >> 1) | (v.hi << 63)
+		v.hi = (v.hi >> 1) ^ (0xe100000000000000 & reduce)
+	}
+	return z
+}
+
+// ghash accumulates GHASH_H over 16-byte blocks.
+type ghash struct {
+	h, y fieldElement
+}
+
+func newGHASH(h []byte) *ghash {
+	return &ghash{h: loadFieldElement(h)}
+}
+
+// update hashes data; a last partial block is padded with zeros.
+func (g *ghash) update(data []byte) {
+	for len(data) > 0 {
+		var block [BlockSize]byte
+		n := copy(block[:], data)
+		data = data[n:]
+		x := loadFieldElement(block[:])
+		g.y.hi ^= x.hi
+		g.y.lo ^= x.lo
+		g.y = gfMul(g.y, g.h)
+	}
+}
+
+// updateLengths hashes the final block with the bit lengths of A and C.
+func (g *ghash) updateLengths(aadLen, textLen int) {
+	var block [BlockSize]byte
+	binary.BigEndian.PutUint64(block[:], uint64(aadLen)*8)
+	binary.BigEndian.PutUint64(block[8:], uint64(textLen)*8)
+	g.update(block[:])
+}
+
+func (g *ghash) sum(out []byte) {
+	g.y.store(out)
+}
@@ -0,0 +1,3 @@
+module github.com/piotrpsz/serpent
+
+go 1.21
@@ -0,0 +1,143 @@
+/*
+	hctr2.go:  Serpent algorithm implementation in Go.
+
+	HCTR2 wide-block tweakable encryption (Crowley, Huckleberry, Biggers)
+	with Serpent in place of AES. The output has the length of the input
+	and every bit of it depends on every bit of the input and the tweak.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+)
+
+// ErrHCTR2Length is returned for an input shorter than one block.
+var ErrHCTR2Length = errors.New("serpent: HCTR2 input shorter than one block")
+
+// HCTR2 is a length-preserving tweakable cipher for inputs of at least
+// one block, such as file names. It keeps no state between calls.
+type HCTR2 struct {
+	b    cipher.Block
+	hbar [BlockSize]byte // POLYVAL key, E(0)
+	l    [BlockSize]byte // E(1)
+}
+
+// NewHCTR2 returns HCTR2 over the block b (see NewCipher).
+func NewHCTR2(b cipher.Block) (*HCTR2, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, errors.New("serpent: HCTR2 requires a 128-bit block cipher")
+	}
+	h := &HCTR2{b: b}
+	b.Encrypt(h.hbar[:], h.hbar[:])
+	h.l[0] = 1
+	b.Encrypt(h.l[:], h.l[:])
+	return h, nil
+}
+
+// Encrypt encrypts src with the tweak into dst, which may be src.
+func (h *HCTR2) Encrypt(dst, src, tweak []byte) error {
+	if len(src) < BlockSize {
+		return ErrHCTR2Length
+	}
+	if len(dst) < len(src) {
+		panic("serpent: output smaller than input")
+	}
+	var mm, uu, s, u [BlockSize]byte
+	h.hash(&mm, tweak, src[BlockSize:])
+	xorBytes(mm[:], mm[:], src[:BlockSize])
+	h.b.Encrypt(uu[:], mm[:])
+
+	xorBytes(s[:], mm[:], uu[:])
+	xorBytes(s[:], s[:], h.l[:])
+	h.xctr(dst[BlockSize:len(src)], src[BlockSize:], &s)
+
+	h.hash(&u, tweak, dst[BlockSize:len(src)])
+	xorBytes(dst, u[:], uu[:])
+	return nil
+}
+
+// Decrypt decrypts src with the tweak into dst, which may be src.
+func (h *HCTR2) Decrypt(dst, src, tweak []byte) error {
+	if len(src) < BlockSize {
+		return ErrHCTR2Length
+	}
+	if len(dst) < len(src) {
+		panic("serpent: output smaller than input")
+	}
+	var mm, uu, s, m [BlockSize]byte
+	h.hash(&uu, tweak, src[BlockSize:])
+	xorBytes(uu[:], uu[:], src[:BlockSize])
+	h.b.Decrypt(mm[:], uu[:])
+
+	xorBytes(s[:], mm[:], uu[:])
+	xorBytes(s[:], s[:], h.l[:])
+	h.xctr(dst[BlockSize:len(src)], src[BlockSize:], &s)
+
+	h.hash(&m, tweak, dst[BlockSize:len(src)])
+	xorBytes(dst, m[:], mm[:])
+	return nil
+}
+
+// hash computes POLYVAL(hbar, len || pad(T) || M) where M is padded
+// with 0x01 and zeros when it is not a multiple of the block size,
+// and len is 2*bits(T)+2, or +3 when M was padded.
+func (h *HCTR2) hash(out *[BlockSize]byte, tweak, m []byte) {
+	var first [BlockSize]byte
+	if len(m)%BlockSize == 0 {
+		binary.LittleEndian.PutUint64(first[:], uint64(len(tweak))*8*2+2)
+	} else {
+		binary.LittleEndian.PutUint64(first[:], uint64(len(tweak))*8*2+3)
+	}
+	p := newPOLYVAL(h.hbar[:])
+	p.update(first[:])
+	p.update(tweak)
+
+	full := len(m) - len(m)%BlockSize
+	p.update(m[:full])
+	if full < len(m) {
+		var last [BlockSize]byte
+		n := copy(last[:], m[full:])
+		last[n] = 1
+		p.update(last[:])
+	}
+	p.sum(out[:])
+}
+
+// xctr xors in with the XCTR keystream E(S ^ LE(1)), E(S ^ LE(2)), ...
+func (h *HCTR2) xctr(out, in []byte, s *[BlockSize]byte) {
+	var counters, stream [ctrBatch * BlockSize]byte
+	i := uint64(1)
+	for len(in) > 0 {
+		n := (len(in) + BlockSize - 1) / BlockSize
+		if n > ctrBatch {
+			n = ctrBatch
+		}
+		for j := 0; j < n; j++ {
+			c := counters[j*BlockSize : (j+1)*BlockSize]
+			copy(c, s[:])
+			binary.LittleEndian.PutUint64(c, binary.LittleEndian.Uint64(s[:8])^i)
+			i++
+		}
+		encryptBlocksWith(h.b, stream[:n*BlockSize], counters[:n*BlockSize])
+		k := xorBytes(out, in, stream[:n*BlockSize])
+		out, in = out[k:], in[k:]
+	}
+}
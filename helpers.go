@@ -154,3 +154,30 @@ func blockToBytes(data []uint) []byte {
 	buffer = append(buffer, uint32ToBytes(uint32(data[3]))...)
 	return buffer
 }
+
+// xorBytes sets dst[i] = a[i] ^ b[i] for the shorter of a and b
+// and returns the number of bytes written.
+func xorBytes(dst, a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		dst[i] = a[i] ^ b[i]
+	}
+	return n
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns
+// a slice with the contents of the given slice followed by that many bytes
+// and a second slice that aliases into it and contains only the extra bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
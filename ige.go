@@ -0,0 +1,91 @@
+/*
+	ige.go:  Serpent algorithm implementation in Go.
+
+	Infinite Garble Extension (IGE) mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"errors"
+)
+
+// IGEIVSize is the size of the IGE iv: two blocks.
+const IGEIVSize = 2 * BlockSize
+
+// EncryptIGE encrypts whole blocks of src into dst in IGE mode:
+//
+//	C[i] = E(P[i] ^ C[i-1]) ^ P[i-1]
+//
+// The 32-byte iv holds C[0] followed by P[0], the layout of OpenSSL
+// and MTProto. dst and src may be the same slice.
+func EncryptIGE(b cipher.Block, dst, src, iv []byte) error {
+	return ige(b, dst, src, iv, false)
+}
+
+// DecryptIGE decrypts whole blocks of src into dst in IGE mode:
+//
+//	P[i] = D(C[i] ^ P[i-1]) ^ C[i-1]
+func DecryptIGE(b cipher.Block, dst, src, iv []byte) error {
+	return ige(b, dst, src, iv, true)
+}
+
+func ige(b cipher.Block, dst, src, iv []byte, decrypt bool) error {
+	if err := checkBlocks(b, dst, src); err != nil {
+		return err
+	}
+	if len(iv) != IGEIVSize {
+		return Error(BAD_IV)
+	}
+	// x is the previous output block and y the previous input block,
+	// so for decryption the two halves of the iv swap roles.
+	var x, y, in, t [BlockSize]byte
+	copy(x[:], iv[:BlockSize])
+	copy(y[:], iv[BlockSize:])
+	if decrypt {
+		x, y = y, x
+	}
+	for i := 0; i < len(src); i += BlockSize {
+		copy(in[:], src[i:i+BlockSize])
+		xorBytes(t[:], in[:], x[:])
+		if decrypt {
+			b.Decrypt(t[:], t[:])
+		} else {
+			b.Encrypt(t[:], t[:])
+		}
+		xorBytes(x[:], t[:], y[:])
+		copy(dst[i:], x[:])
+		y = in
+	}
+	return nil
+}
+
+// checkBlocks validates the arguments of the whole-block modes.
+func checkBlocks(b cipher.Block, dst, src []byte) error {
+	if b.BlockSize() != BlockSize {
+		return errors.New("serpent: mode requires a 128-bit block cipher")
+	}
+	if len(src)%BlockSize != 0 {
+		return Error(BAD_LENGTH)
+	}
+	if len(dst) < len(src) {
+		panic("serpent: output smaller than input")
+	}
+	return nil
+}
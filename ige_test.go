@@ -0,0 +1,117 @@
+/*
+	ige_test.go:  Unit tests of the IGE mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+// AES-IGE test vectors of OpenSSL (test/igetest.c).
+func TestIGEWithAES(t *testing.T) {
+	tests := []struct {
+		key, iv, plainText, cipherText string
+	}{
+		{
+			"000102030405060708090a0b0c0d0e0f",
+			"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"1a8519a6557be652e9da8e43da4ef4453cf456b4ca488aa383c79c98b34797cb",
+		},
+		{
+			"5468697320697320616e20696d706c65",
+			"6d656e746174696f6e206f6620494745206d6f646520666f72204f70656e5353",
+			"99706487a1cde613bc6de0b6f24b1c7aa448c8b9c3403e3467a8cad89340f53b",
+			"4c2e204c6574277320686f70652042656e20676f74206974207269676874210a",
+		},
+	}
+	for i, tt := range tests {
+		block, _ := aes.NewCipher(mustHex(t, tt.key))
+		iv := mustHex(t, tt.iv)
+		plainText := mustHex(t, tt.plainText)
+		expected := mustHex(t, tt.cipherText)
+
+		out := make([]byte, len(plainText))
+		if err := EncryptIGE(block, out, plainText, iv); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, expected) {
+			t.Errorf("%d: Is %x, should: %x", i, out, expected)
+		}
+		if err := DecryptIGE(block, out, out, iv); err != nil || !bytes.Equal(out, plainText) {
+			t.Errorf("%d: decrypted. Is (%x, %v), should: %x", i, out, err, plainText)
+		}
+	}
+}
+
+// garbledBlocks returns which blocks of a and b differ.
+func garbledBlocks(a, b []byte) []bool {
+	var garbled []bool
+	for i := 0; i < len(a); i += BlockSize {
+		garbled = append(garbled, !bytes.Equal(a[i:i+BlockSize], b[i:i+BlockSize]))
+	}
+	return garbled
+}
+
+// A changed ciphertext block garbles every following plaintext block
+// in IGE, but only two blocks in CBC.
+func TestIGEErrorPropagation(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	iv2 := append(append([]byte(nil), iv...), rawKey[:BlockSize]...)
+	plainText := testMessage(8 * BlockSize)
+
+	cipherText := make([]byte, len(plainText))
+	EncryptIGE(block, cipherText, plainText, iv2)
+	cipherText[2*BlockSize] ^= 1
+	out := make([]byte, len(plainText))
+	DecryptIGE(block, out, cipherText, iv2)
+	for i, garbled := range garbledBlocks(out, plainText) {
+		if garbled != (i >= 2) {
+			t.Errorf("IGE block %d garbled: %v, should: %v", i, garbled, i >= 2)
+		}
+	}
+
+	var x [BlockSize]byte
+	copy(x[:], iv)
+	cbcEncrypt(block, &x, cipherText, plainText)
+	cipherText[2*BlockSize] ^= 1
+	copy(x[:], iv)
+	cbcDecrypt(block, &x, out, cipherText)
+	for i, garbled := range garbledBlocks(out, plainText) {
+		if garbled != (i == 2 || i == 3) {
+			t.Errorf("CBC block %d garbled: %v, should: %v", i, garbled, i == 2 || i == 3)
+		}
+	}
+}
+
+func TestIGEErrors(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	buf := make([]byte, 2*BlockSize)
+	if err := EncryptIGE(block, buf, buf, iv); err != Error(BAD_IV) {
+		t.Errorf("Short IV. Error is %v, should: %v", err, Error(BAD_IV))
+	}
+	if err := EncryptIGE(block, buf, buf[:20], buf); err != Error(BAD_LENGTH) {
+		t.Errorf("Partial block. Error is %v, should: %v", err, Error(BAD_LENGTH))
+	}
+}
@@ -0,0 +1,164 @@
+/*
+	keywrap.go:  Serpent algorithm implementation in Go.
+
+	Key wrap (RFC 3394) and key wrap with padding (RFC 5649).
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const keyWrapSemiblock = 8
+
+var (
+	keyWrapIV    = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+	keyWrapPadIV = []byte{0xa6, 0x59, 0x59, 0xa6}
+
+	// ErrKeyWrapLength is returned for input of a length the
+	// key wrap function does not take.
+	ErrKeyWrapLength = errors.New("serpent: invalid key wrap input length")
+)
+
+// UnwrapError is returned when the integrity check value of a wrapped
+// key does not match. It does not tell which part of the check failed.
+type UnwrapError struct{}
+
+func (UnwrapError) Error() string {
+	return "serpent: key unwrap integrity check failed"
+}
+
+// Wrap wraps key with the key-encryption block kek (RFC 3394).
+// The key must be at least 16 bytes long and a multiple of 8 bytes.
+func Wrap(kek cipher.Block, key []byte) ([]byte, error) {
+	if len(key) < 2*keyWrapSemiblock || len(key)%keyWrapSemiblock != 0 {
+		return nil, ErrKeyWrapLength
+	}
+	out := make([]byte, keyWrapSemiblock+len(key))
+	copy(out, keyWrapIV)
+	copy(out[keyWrapSemiblock:], key)
+	wrap(kek, out)
+	return out, nil
+}
+
+// Unwrap unwraps a key wrapped by Wrap.
+func Unwrap(kek cipher.Block, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 3*keyWrapSemiblock || len(wrapped)%keyWrapSemiblock != 0 {
+		return nil, ErrKeyWrapLength
+	}
+	buf := append([]byte(nil), wrapped...)
+	unwrap(kek, buf)
+	if subtle.ConstantTimeCompare(buf[:keyWrapSemiblock], keyWrapIV) != 1 {
+		return nil, UnwrapError{}
+	}
+	return buf[keyWrapSemiblock:], nil
+}
+
+// WrapPad wraps a key of any non-zero length with padding (RFC 5649).
+func WrapPad(kek cipher.Block, key []byte) ([]byte, error) {
+	if len(key) == 0 || uint64(len(key)) > 1<<32-1 {
+		return nil, ErrKeyWrapLength
+	}
+	padded := (len(key) + keyWrapSemiblock - 1) / keyWrapSemiblock * keyWrapSemiblock
+	out := make([]byte, keyWrapSemiblock+padded)
+	copy(out, keyWrapPadIV)
+	binary.BigEndian.PutUint32(out[4:], uint32(len(key)))
+	copy(out[keyWrapSemiblock:], key)
+
+	if padded == keyWrapSemiblock {
+		kek.Encrypt(out, out)
+	} else {
+		wrap(kek, out)
+	}
+	return out, nil
+}
+
+// UnwrapPad unwraps a key wrapped by WrapPad.
+func UnwrapPad(kek cipher.Block, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 2*keyWrapSemiblock || len(wrapped)%keyWrapSemiblock != 0 {
+		return nil, ErrKeyWrapLength
+	}
+	buf := append([]byte(nil), wrapped...)
+	if len(buf) == 2*keyWrapSemiblock {
+		kek.Decrypt(buf, buf)
+	} else {
+		unwrap(kek, buf)
+	}
+
+	// All checks are evaluated before the result is used.
+	n := len(buf) - keyWrapSemiblock
+	mli := binary.BigEndian.Uint32(buf[4:])
+	ok := subtle.ConstantTimeCompare(buf[:4], keyWrapPadIV)
+	inRange := 0
+	if uint64(mli) > uint64(n-keyWrapSemiblock) && uint64(mli) <= uint64(n) {
+		inRange = 1
+	}
+	ok &= inRange
+	// Bytes past the message length must be zero.
+	limit := subtle.ConstantTimeSelect(inRange, int(mli), n)
+	var padding byte
+	for i := 0; i < n; i++ {
+		mask := byte(-subtle.ConstantTimeLessOrEq(limit, i))
+		padding |= buf[keyWrapSemiblock+i] & mask
+	}
+	ok &= subtle.ConstantTimeByteEq(padding, 0)
+	if ok != 1 {
+		return nil, UnwrapError{}
+	}
+	return buf[keyWrapSemiblock : keyWrapSemiblock+int(mli)], nil
+}
+
+// wrap runs the wrapping function W over buf = A || R[1] || ... || R[n].
+func wrap(kek cipher.Block, buf []byte) {
+	n := len(buf)/keyWrapSemiblock - 1
+	var b [BlockSize]byte
+	copy(b[:keyWrapSemiblock], buf)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			r := buf[i*keyWrapSemiblock : (i+1)*keyWrapSemiblock]
+			copy(b[keyWrapSemiblock:], r)
+			kek.Encrypt(b[:], b[:])
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b[:], binary.BigEndian.Uint64(b[:])^t)
+			copy(r, b[keyWrapSemiblock:])
+		}
+	}
+	copy(buf, b[:keyWrapSemiblock])
+}
+
+// unwrap runs the inverse function W^-1 over buf.
+func unwrap(kek cipher.Block, buf []byte) {
+	n := len(buf)/keyWrapSemiblock - 1
+	var b [BlockSize]byte
+	copy(b[:keyWrapSemiblock], buf)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			r := buf[i*keyWrapSemiblock : (i+1)*keyWrapSemiblock]
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b[:], binary.BigEndian.Uint64(b[:])^t)
+			copy(b[keyWrapSemiblock:], r)
+			kek.Decrypt(b[:], b[:])
+			copy(r, b[keyWrapSemiblock:])
+		}
+	}
+	copy(buf, b[:keyWrapSemiblock])
+}
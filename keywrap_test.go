@@ -0,0 +1,181 @@
+/*
+	keywrap_test.go:  Unit tests of the key wrap functions.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+// Test vectors of RFC 3394 section 4 and RFC 5649 section 6 (AES).
+func TestKeyWrapWithAES(t *testing.T) {
+	tests := []struct {
+		pad     bool
+		kek     string
+		key     string
+		wrapped string
+	}{
+		{
+			false,
+			"000102030405060708090a0b0c0d0e0f",
+			"00112233445566778899aabbccddeeff",
+			"1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5",
+		},
+		{
+			false,
+			"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"00112233445566778899aabbccddeeff000102030405060708090a0b0c0d0e0f",
+			"28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21",
+		},
+		{
+			true,
+			"5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+			"c37b7e6492584340bed12207808941155068f738",
+			"138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a",
+		},
+		{
+			true,
+			"5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+			"466f7250617369",
+			"afbeb0f07dfbf5419200f2ccb50bb24f",
+		},
+	}
+	for i, tt := range tests {
+		kek, _ := aes.NewCipher(mustHex(t, tt.kek))
+		key := mustHex(t, tt.key)
+		expected := mustHex(t, tt.wrapped)
+
+		wrapFn, unwrapFn := Wrap, Unwrap
+		if tt.pad {
+			wrapFn, unwrapFn = WrapPad, UnwrapPad
+		}
+		wrapped, err := wrapFn(kek, key)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if !bytes.Equal(wrapped, expected) {
+			t.Errorf("%d: wrapped key. Is %x, should: %x", i, wrapped, expected)
+		}
+		unwrapped, err := unwrapFn(kek, expected)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if !bytes.Equal(unwrapped, key) {
+			t.Errorf("%d: unwrapped key. Is %x, should: %x", i, unwrapped, key)
+		}
+	}
+}
+
+func TestKeyWrap(t *testing.T) {
+	key, _ := testKeyAndIV()
+	kek, _ := NewCipher(key)
+
+	for n := 16; n <= 64; n += 8 {
+		data := testMessage(n)
+		wrapped, err := Wrap(kek, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(wrapped) != n+8 {
+			t.Errorf("%d: wrapped length. Is %d, should: %d", n, len(wrapped), n+8)
+		}
+		unwrapped, err := Unwrap(kek, wrapped)
+		if err != nil {
+			t.Fatalf("%d: %v", n, err)
+		}
+		if !bytes.Equal(unwrapped, data) {
+			t.Errorf("%d: unwrapped key. Is %x, should: %x", n, unwrapped, data)
+		}
+		wrapped[len(wrapped)-1] ^= 1
+		if _, err := Unwrap(kek, wrapped); err != (UnwrapError{}) {
+			t.Errorf("%d: tampered key. Is %v, should: %v", n, err, UnwrapError{})
+		}
+	}
+	for _, n := range []int{0, 8, 15, 17} {
+		if _, err := Wrap(kek, make([]byte, n)); err != ErrKeyWrapLength {
+			t.Errorf("%d: Is %v, should: %v", n, err, ErrKeyWrapLength)
+		}
+	}
+}
+
+func TestKeyWrapPad(t *testing.T) {
+	key, _ := testKeyAndIV()
+	kek, _ := NewCipher(key)
+
+	for n := 1; n <= 40; n++ {
+		data := testMessage(n)
+		wrapped, err := WrapPad(kek, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := (n+7)/8*8 + 8; len(wrapped) != expected {
+			t.Errorf("%d: wrapped length. Is %d, should: %d", n, len(wrapped), expected)
+		}
+		unwrapped, err := UnwrapPad(kek, wrapped)
+		if err != nil {
+			t.Fatalf("%d: %v", n, err)
+		}
+		if !bytes.Equal(unwrapped, data) {
+			t.Errorf("%d: unwrapped key. Is %x, should: %x", n, unwrapped, data)
+		}
+		wrapped[0] ^= 0x80
+		if _, err := UnwrapPad(kek, wrapped); err != (UnwrapError{}) {
+			t.Errorf("%d: tampered key. Is %v, should: %v", n, err, UnwrapError{})
+		}
+	}
+	if _, err := WrapPad(kek, nil); err != ErrKeyWrapLength {
+		t.Errorf("empty key. Is %v, should: %v", err, ErrKeyWrapLength)
+	}
+
+	// A valid RFC 3394 wrapping must not pass the RFC 5649 check.
+	wrapped, _ := Wrap(kek, testMessage(24))
+	if _, err := UnwrapPad(kek, wrapped); err != (UnwrapError{}) {
+		t.Errorf("unpadded wrapping. Is %v, should: %v", err, UnwrapError{})
+	}
+}
+
+// Nonzero padding or a length out of range must fail the integrity check.
+func TestKeyWrapPadInvalid(t *testing.T) {
+	key, _ := testKeyAndIV()
+	kek, _ := NewCipher(key)
+
+	build := func(mli uint32, data []byte) []byte {
+		buf := make([]byte, 8+len(data))
+		copy(buf, keyWrapPadIV)
+		buf[4], buf[5], buf[6], buf[7] = byte(mli>>24), byte(mli>>16), byte(mli>>8), byte(mli)
+		copy(buf[8:], data)
+		wrap(kek, buf)
+		return buf
+	}
+	data := testMessage(16)
+	if _, err := UnwrapPad(kek, build(13, data)); err != (UnwrapError{}) {
+		t.Errorf("nonzero padding. Is %v, should: %v", err, UnwrapError{})
+	}
+	for _, mli := range []uint32{0, 8, 17, 1 << 31} {
+		if _, err := UnwrapPad(kek, build(mli, data)); err != (UnwrapError{}) {
+			t.Errorf("length %d. Is %v, should: %v", mli, err, UnwrapError{})
+		}
+	}
+	if got, err := UnwrapPad(kek, build(16, data)); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("valid wrapping. Is %x (%v), should: %x", got, err, data)
+	}
+}
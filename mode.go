@@ -0,0 +1,187 @@
+/*
+	mode.go:  Serpent algorithm implementation in Go.
+
+	Modes of the AES submission API (cipherInit, blockEncrypt, blockDecrypt).
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import "strconv"
+
+// Error is an error code of the submission API (BAD_IV, BAD_LENGTH, ...)
+// returned as a Go error.
+type Error int
+
+func (e Error) Error() string {
+	switch e {
+	case BAD_KEY_DIR:
+		return "serpent: bad key direction"
+	case BAD_KEY_MAT:
+		return "serpent: bad key material"
+	case BAD_KEY_INSTANCE:
+		return "serpent: bad key instance"
+	case BAD_CIPHER_MODE:
+		return "serpent: bad cipher mode"
+	case BAD_CIPHER_STATE:
+		return "serpent: bad cipher state"
+	case BAD_HEX_DIGIT:
+		return "serpent: bad hex digit"
+	case BAD_LENGTH:
+		return "serpent: input is not a multiple of the block size"
+	case BAD_IV:
+		return "serpent: bad IV length"
+	case BAD_NUMBER_OF_BITS_PROCESSED:
+		return "serpent: bad number of bits processed"
+	case BAD_INPUT:
+		return "serpent: bad input"
+	}
+	return "serpent: error " + strconv.Itoa(int(e))
+}
+
+// CipherInit returns a cipher instance for the given mode.
+// For MODE_CBC and MODE_CFB1 the iv must have BYTES_PER_IV bytes,
+// for MODE_ECB it is ignored.
+func CipherInit(mode byte, iv []byte) (*cipherInstance, error) {
+	ci := newCipherInstance()
+	switch mode {
+	case MODE_ECB:
+	case MODE_CBC, MODE_CFB1:
+		if len(iv) != BYTES_PER_IV {
+			return nil, Error(BAD_IV)
+		}
+		copy(ci.iv[:], iv)
+	default:
+		return nil, Error(BAD_CIPHER_MODE)
+	}
+	ci.mode = mode
+	ci.blockSize = BITS_PER_BLOCK
+	return ci, nil
+}
+
+// CipherEncrypt encrypts inputLen bits of input into output
+// and returns the number of bits processed.
+// For MODE_ECB and MODE_CBC inputLen must be a multiple of BITS_PER_BLOCK,
+// MODE_CFB1 takes any number of bits (most significant bit of a byte first).
+// Input and output may be the same slice. In CBC and CFB1 mode the instance
+// keeps its feedback register, so the next call continues the chain.
+func CipherEncrypt(ci *cipherInstance, key *keyInstance, input []byte, inputLen int, output []byte) (int, error) {
+	if ci.mode == MODE_CFB1 {
+		return cfb1(ci, key, input, inputLen, output, false)
+	}
+	if err := checkLength(input, inputLen, output); err != nil {
+		return 0, err
+	}
+	n := inputLen / BITS_PER_BYTE
+
+	switch ci.mode {
+	case MODE_ECB:
+		encryptBlocks(key, output[:n], input[:n])
+	case MODE_CBC:
+		for i := 0; i < n; i += BYTES_PER_BLOCK {
+			for j := 0; j < BYTES_PER_BLOCK; j++ {
+				ci.iv[j] ^= input[i+j]
+			}
+			x := loadBlock(ci.iv[:])
+			encryptBitslice(&key.subkeys, &x)
+			storeBlock(ci.iv[:], &x)
+			copy(output[i:], ci.iv[:])
+		}
+	default:
+		return 0, Error(BAD_CIPHER_STATE)
+	}
+	return inputLen, nil
+}
+
+// CipherDecrypt decrypts inputLen bits of input into output
+// and returns the number of bits processed (see CipherEncrypt).
+func CipherDecrypt(ci *cipherInstance, key *keyInstance, input []byte, inputLen int, output []byte) (int, error) {
+	if ci.mode == MODE_CFB1 {
+		return cfb1(ci, key, input, inputLen, output, true)
+	}
+	if err := checkLength(input, inputLen, output); err != nil {
+		return 0, err
+	}
+	n := inputLen / BITS_PER_BYTE
+
+	switch ci.mode {
+	case MODE_ECB:
+		decryptBlocks(key, output[:n], input[:n])
+	case MODE_CBC:
+		var c [BYTES_PER_BLOCK]byte
+		for i := 0; i < n; i += BYTES_PER_BLOCK {
+			copy(c[:], input[i:])
+			x := loadBlock(c[:])
+			decryptBitslice(&key.subkeys, &x)
+			storeBlock(output[i:], &x)
+			for j := 0; j < BYTES_PER_BLOCK; j++ {
+				output[i+j] ^= ci.iv[j]
+			}
+			ci.iv = c
+		}
+	default:
+		return 0, Error(BAD_CIPHER_STATE)
+	}
+	return inputLen, nil
+}
+
+func checkLength(input []byte, inputLen int, output []byte) error {
+	if inputLen < 0 || (inputLen%BITS_PER_BLOCK) > 0 {
+		return Error(BAD_LENGTH)
+	}
+	n := inputLen / BITS_PER_BYTE
+	if len(input) < n || len(output) < n {
+		return Error(BAD_LENGTH)
+	}
+	return nil
+}
+
+// cfb1 processes inputLen bits one at a time. For every bit the IV register
+// is encrypted, the top bit of the result is xored with the input bit
+// and the register is shifted left by one bit, taking in the cipher bit.
+//
+// The register and the encrypted block are 128-bit numbers in the word
+// order of the reference code: four little-endian words, word 0 lowest.
+// So the top bit is bit 7 of byte 15, and the new bit enters at bit 0 of
+// byte 0. Input and output bits are taken most significant bit of a byte
+// first.
+func cfb1(ci *cipherInstance, key *keyInstance, input []byte, inputLen int, output []byte, decrypt bool) (int, error) {
+	if inputLen < 0 || inputLen > len(input)*BITS_PER_BYTE || inputLen > len(output)*BITS_PER_BYTE {
+		return 0, Error(BAD_NUMBER_OF_BITS_PROCESSED)
+	}
+	var block [BYTES_PER_BLOCK]byte
+	for i := 0; i < inputLen; i++ {
+		x := loadBlock(ci.iv[:])
+		encryptBitslice(&key.subkeys, &x)
+		storeBlock(block[:], &x)
+
+		shift := uint(7 - i%BITS_PER_BYTE)
+		in := (input[i/BITS_PER_BYTE] >> shift) & 1
+		out := in ^ (block[BYTES_PER_BLOCK-1] >> 7)
+		output[i/BITS_PER_BYTE] = (output[i/BITS_PER_BYTE] &^ (1 << shift)) | (out << shift)
+
+		feedback := out
+		if decrypt {
+			feedback = in
+		}
+		for j := BYTES_PER_IV - 1; j > 0; j-- {
+			ci.iv[j] = (ci.iv[j] << 1) | (ci.iv[j-1] >> 7)
+		}
+		ci.iv[0] = (ci.iv[0] << 1) | feedback
+	}
+	return inputLen, nil
+}
@@ -0,0 +1,343 @@
+/*
+	mode_test.go:  Unit tests of the submission API modes.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"math/big"
+	"testing"
+)
+
+func testKeyAndIV() ([]byte, []byte) {
+	key := make([]byte, 32)
+	iv := make([]byte, BYTES_PER_IV)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for i := range iv {
+		iv[i] = byte(0xf0 + i)
+	}
+	return key, iv
+}
+
+func testMessage(n int) []byte {
+	msg := make([]byte, n)
+	for i := range msg {
+		msg[i] = byte(i*7 + 3)
+	}
+	return msg
+}
+
+// CBC must agree with the generic crypto/cipher CBC over the same block;
+// fixed vectors are in TestCBCVectors and TestCBCMonteCarlo.
+func TestCBC(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	key, _ := NewKey(rawKey)
+	block, _ := NewCipher(rawKey)
+
+	for _, blocks := range []int{0, 1, 2, 5} {
+		plainText := testMessage(blocks * BYTES_PER_BLOCK)
+		expected := make([]byte, len(plainText))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(expected, plainText)
+
+		ci, err := CipherInit(MODE_CBC, iv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output := make([]byte, len(plainText))
+		n, err := CipherEncrypt(ci, key, plainText, len(plainText)*8, output)
+		if err != nil || n != len(plainText)*8 {
+			t.Fatalf("CipherEncrypt. Is (%d, %v), should: (%d, nil)", n, err, len(plainText)*8)
+		}
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d blocks. Is %x, should: %x", blocks, output, expected)
+		}
+
+		// in place
+		ci, _ = CipherInit(MODE_CBC, iv)
+		if _, err := CipherDecrypt(ci, key, output, len(output)*8, output); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(output, plainText) {
+			t.Errorf("%d blocks. Is %x, should: %x", blocks, output, plainText)
+		}
+	}
+}
+
+// Consecutive calls continue the chain.
+func TestCBCChain(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	key, _ := NewKey(rawKey)
+	plainText := testMessage(4 * BYTES_PER_BLOCK)
+
+	ci, _ := CipherInit(MODE_CBC, iv)
+	expected := make([]byte, len(plainText))
+	CipherEncrypt(ci, key, plainText, len(plainText)*8, expected)
+
+	ci, _ = CipherInit(MODE_CBC, iv)
+	output := make([]byte, len(plainText))
+	CipherEncrypt(ci, key, plainText[:16], 128, output[:16])
+	CipherEncrypt(ci, key, plainText[16:], 3*128, output[16:])
+	if !bytes.Equal(output, expected) {
+		t.Errorf("Is %x, should: %x", output, expected)
+	}
+
+	ci, _ = CipherInit(MODE_CBC, iv)
+	CipherDecrypt(ci, key, output[:32], 256, output[:32])
+	CipherDecrypt(ci, key, output[32:], 256, output[32:])
+	if !bytes.Equal(output, plainText) {
+		t.Errorf("Is %x, should: %x", output, plainText)
+	}
+}
+
+// Fixtures generated with libgcrypt (GCRY_CIPHER_MODE_CBC).
+func TestCBCVectors(t *testing.T) {
+	for i, v := range readVectors(t, "cbc.txt") {
+		rawKey, iv, plainText, cipherText := v[0], v[1], v[2], v[3]
+		key, err := NewKey(rawKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ci, _ := CipherInit(MODE_CBC, iv)
+		output := make([]byte, len(plainText))
+		CipherEncrypt(ci, key, plainText, len(plainText)*8, output)
+		if !bytes.Equal(output, cipherText) {
+			t.Errorf("%d: Is %x, should: %x", i, output, cipherText)
+		}
+		ci, _ = CipherInit(MODE_CBC, iv)
+		CipherDecrypt(ci, key, output, len(output)*8, output)
+		if !bytes.Equal(output, plainText) {
+			t.Errorf("%d: decrypted. Is %x, should: %x", i, output, plainText)
+		}
+	}
+}
+
+// Monte Carlo chains in the style of cbc_e_m and cbc_d_m of the AES
+// submission tests, one block per call, 10000 blocks. Encryption:
+// PT[0] = 0, PT[j+1] = CT[j-1] (CT[-1] = IV); decryption swaps the roles
+// of PT and CT. The final blocks were computed with libgcrypt.
+func TestCBCMonteCarlo(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	key, _ := NewKey(rawKey)
+	tests := []struct {
+		name     string
+		crypt    func(*cipherInstance, *keyInstance, []byte, int, []byte) (int, error)
+		expected string
+	}{
+		{"Encrypt", CipherEncrypt, "399bedf2bb72e18a3e73ae1b3cd902b9"},
+		{"Decrypt", CipherDecrypt, "fcb81fff75bd394e554a8ffdc0351748"},
+	}
+	for _, tt := range tests {
+		ci, _ := CipherInit(MODE_CBC, iv)
+		input := make([]byte, BYTES_PER_BLOCK)
+		prev := append([]byte(nil), iv...)
+		output := make([]byte, BYTES_PER_BLOCK)
+		for j := 0; j < 10000; j++ {
+			tt.crypt(ci, key, input, BITS_PER_BLOCK, output)
+			copy(input, prev)
+			copy(prev, output)
+		}
+		if expected := mustHex(t, tt.expected); !bytes.Equal(output, expected) {
+			t.Errorf("%s. Is %x, should: %x", tt.name, output, expected)
+		}
+	}
+}
+
+func TestECB(t *testing.T) {
+	rawKey, _ := testKeyAndIV()
+	key, _ := NewKey(rawKey)
+	block, _ := NewCipher(rawKey)
+	plainText := testMessage(3 * BYTES_PER_BLOCK)
+
+	ci, err := CipherInit(MODE_ECB, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := make([]byte, len(plainText))
+	CipherEncrypt(ci, key, plainText, len(plainText)*8, output)
+	for i := 0; i < len(plainText); i += BYTES_PER_BLOCK {
+		expected := make([]byte, BYTES_PER_BLOCK)
+		block.Encrypt(expected, plainText[i:])
+		if !bytes.Equal(output[i:i+BYTES_PER_BLOCK], expected) {
+			t.Errorf("Block %d. Is %x, should: %x", i/BYTES_PER_BLOCK, output[i:i+BYTES_PER_BLOCK], expected)
+		}
+	}
+	CipherDecrypt(ci, key, output, len(output)*8, output)
+	if !bytes.Equal(output, plainText) {
+		t.Errorf("Is %x, should: %x", output, plainText)
+	}
+}
+
+func TestCipherInitErrors(t *testing.T) {
+	_, iv := testKeyAndIV()
+	if _, err := CipherInit(MODE_CBC, iv[:15]); err != Error(BAD_IV) {
+		t.Errorf("Short IV. Error is %v, should: %v", err, Error(BAD_IV))
+	}
+	if _, err := CipherInit(MODE_CBC, append(iv, 0)); err != Error(BAD_IV) {
+		t.Errorf("Long IV. Error is %v, should: %v", err, Error(BAD_IV))
+	}
+	if _, err := CipherInit(9, iv); err != Error(BAD_CIPHER_MODE) {
+		t.Errorf("Bad mode. Error is %v, should: %v", err, Error(BAD_CIPHER_MODE))
+	}
+}
+
+func TestCipherEncryptPartialBlock(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	key, _ := NewKey(rawKey)
+	ci, _ := CipherInit(MODE_CBC, iv)
+	buf := make([]byte, 32)
+
+	for _, bits := range []int{8, 120, 136, 255, -128} {
+		if _, err := CipherEncrypt(ci, key, buf, bits, buf); err != Error(BAD_LENGTH) {
+			t.Errorf("%d bits. Error is %v, should: %v", bits, err, Error(BAD_LENGTH))
+		}
+		if _, err := CipherDecrypt(ci, key, buf, bits, buf); err != Error(BAD_LENGTH) {
+			t.Errorf("%d bits. Error is %v, should: %v", bits, err, Error(BAD_LENGTH))
+		}
+	}
+	// buffers shorter than inputLen
+	if _, err := CipherEncrypt(ci, key, buf[:16], 256, buf); err != Error(BAD_LENGTH) {
+		t.Errorf("Short input. Error is %v, should: %v", err, Error(BAD_LENGTH))
+	}
+}
+
+// cfb1Oracle computes CFB1 with the feedback register kept as a big integer;
+// blocks are little-endian 128-bit numbers, as in the reference code.
+func cfb1Oracle(block cipher.Block, iv, input []byte, bits int) []byte {
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	register := new(big.Int).SetBytes(reverseBytes(iv))
+	output := make([]byte, len(input))
+	buf := make([]byte, BYTES_PER_BLOCK)
+	for i := 0; i < bits; i++ {
+		register.FillBytes(buf)
+		copy(buf, reverseBytes(buf))
+		block.Encrypt(buf, buf)
+		bit := uint((input[i/8]>>uint(7-i%8))&1) ^ uint(buf[BYTES_PER_BLOCK-1]>>7)
+		output[i/8] |= byte(bit) << uint(7-i%8)
+		register.Lsh(register, 1).Or(register, big.NewInt(int64(bit))).And(register, mask)
+	}
+	return output
+}
+
+func TestCFB1(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	key, _ := NewKey(rawKey)
+	block, _ := NewCipher(rawKey)
+
+	for _, bits := range []int{0, 1, 7, 8, 13, 128, 200, 256} {
+		plainText := testMessage((bits + 7) / 8)
+		expected := cfb1Oracle(block, iv, plainText, bits)
+
+		ci, err := CipherInit(MODE_CFB1, iv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output := make([]byte, len(plainText))
+		n, err := CipherEncrypt(ci, key, plainText, bits, output)
+		if err != nil || n != bits {
+			t.Fatalf("CipherEncrypt. Is (%d, %v), should: (%d, nil)", n, err, bits)
+		}
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d bits. Is %x, should: %x", bits, output, expected)
+		}
+
+		// in place
+		ci, _ = CipherInit(MODE_CFB1, iv)
+		if _, err := CipherDecrypt(ci, key, output, bits, output); err != nil {
+			t.Fatal(err)
+		}
+		// only the first bits of a last partial byte are significant
+		if n := len(output); bits%8 != 0 {
+			mask := byte(0xff << uint(8-bits%8))
+			output[n-1] &= mask
+			plainText[n-1] &= mask
+		}
+		if !bytes.Equal(output, plainText) {
+			t.Errorf("%d bits. Is %x, should: %x", bits, output, plainText)
+		}
+	}
+}
+
+// Fixed answers for the bit order documented on cfb1, computed outside
+// this package over libgcrypt's Serpent ECB with the register and the
+// encrypted block read as little-endian 128-bit numbers.
+func TestCFB1Vectors(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	key, _ := NewKey(rawKey)
+	vectors := []struct {
+		bits     int
+		expected string
+	}{
+		{1, "80"},
+		{7, "de"},
+		{8, "de"},
+		{13, "de38"},
+		{128, "de3ce3d55b77f181a9954a27755a3134"},
+		{200, "de3ce3d55b77f181a9954a27755a3134d00f82e6fd67272f42"},
+	}
+	for _, v := range vectors {
+		plainText := testMessage((v.bits + 7) / 8)
+		expected := mustHex(t, v.expected)
+
+		ci, _ := CipherInit(MODE_CFB1, iv)
+		output := make([]byte, len(plainText))
+		CipherEncrypt(ci, key, plainText, v.bits, output)
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d bits. Is %x, should: %x", v.bits, output, expected)
+		}
+	}
+}
+
+// Splitting the input in the middle of a byte continues the feedback.
+func TestCFB1Chain(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	key, _ := NewKey(rawKey)
+	plainText := testMessage(4)
+
+	ci, _ := CipherInit(MODE_CFB1, iv)
+	expected := make([]byte, len(plainText))
+	CipherEncrypt(ci, key, plainText, 32, expected)
+
+	ci, _ = CipherInit(MODE_CFB1, iv)
+	output := make([]byte, len(plainText))
+	CipherEncrypt(ci, key, plainText[:1], 8, output[:1])
+	CipherEncrypt(ci, key, plainText[1:], 24, output[1:])
+	if !bytes.Equal(output, expected) {
+		t.Errorf("Is %x, should: %x", output, expected)
+	}
+}
+
+func TestCFB1BadNumberOfBits(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	key, _ := NewKey(rawKey)
+	ci, _ := CipherInit(MODE_CFB1, iv)
+	buf := make([]byte, 2)
+
+	for _, bits := range []int{-1, 17, 64} {
+		if _, err := CipherEncrypt(ci, key, buf, bits, buf); err != Error(BAD_NUMBER_OF_BITS_PROCESSED) {
+			t.Errorf("%d bits. Error is %v, should: %v", bits, err, Error(BAD_NUMBER_OF_BITS_PROCESSED))
+		}
+	}
+	if _, err := CipherInit(MODE_CFB1, iv[:8]); err != Error(BAD_IV) {
+		t.Errorf("Short IV. Error is %v, should: %v", err, Error(BAD_IV))
+	}
+}
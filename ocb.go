@@ -0,0 +1,235 @@
+/*
+	ocb.go:  Serpent algorithm implementation in Go.
+
+	OCB3 authenticated encryption (RFC 7253).
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"math/bits"
+)
+
+const (
+	ocbNonceSize    = 12
+	ocbMaxNonceSize = 15
+	ocbTagSize      = 16
+	ocbBatch        = 8
+)
+
+type ocb struct {
+	b         cipher.Block
+	nonceSize int
+	tagSize   int
+	lStar     [BlockSize]byte
+	lDollar   [BlockSize]byte
+	l         [64][BlockSize]byte // L_i = double^i(L_$ doubled)
+}
+
+// NewOCB returns the block b wrapped in OCB3 mode with a 96-bit nonce
+// and a 128-bit tag.
+func NewOCB(b cipher.Block) (cipher.AEAD, error) {
+	return NewOCBWithSizes(b, ocbNonceSize, ocbTagSize)
+}
+
+// NewOCBWithSizes returns OCB3 with nonces of nonceSize bytes (1 to 15)
+// and tags of tagSize bytes (1 to 16).
+func NewOCBWithSizes(b cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, errors.New("serpent: OCB requires a 128-bit block cipher")
+	}
+	if nonceSize < 1 || nonceSize > ocbMaxNonceSize {
+		return nil, errors.New("serpent: invalid OCB nonce size")
+	}
+	if tagSize < 1 || tagSize > ocbTagSize {
+		return nil, errors.New("serpent: invalid OCB tag size")
+	}
+	o := &ocb{b: b, nonceSize: nonceSize, tagSize: tagSize}
+	b.Encrypt(o.lStar[:], o.lStar[:])
+	double(&o.lDollar, &o.lStar)
+	double(&o.l[0], &o.lDollar)
+	for i := 1; i < len(o.l); i++ {
+		double(&o.l[i], &o.l[i-1])
+	}
+	return o, nil
+}
+
+// double multiplies by x in GF(2^128) with the big-endian convention
+// of OCB, CMAC and PMAC.
+func double(out, in *[BlockSize]byte) {
+	carry := in[0] >> 7
+	for i := 0; i < BlockSize-1; i++ {
+		out[i] = (in[i] << 1) | (in[i+1] >> 7)
+	}
+	out[BlockSize-1] = (in[BlockSize-1] << 1) ^ (0x87 & -carry)
+}
+
+func (o *ocb) NonceSize() int {
+	return o.nonceSize
+}
+
+func (o *ocb) Overhead() int {
+	return o.tagSize
+}
+
+func (o *ocb) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != o.nonceSize {
+		panic("serpent: incorrect nonce length given to OCB")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+o.tagSize)
+
+	var tag [BlockSize]byte
+	o.crypt(out, plaintext, nonce, &tag, false)
+	o.hash(&tag, additionalData)
+	copy(out[len(plaintext):], tag[:o.tagSize])
+	return ret
+}
+
+func (o *ocb) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != o.nonceSize {
+		panic("serpent: incorrect nonce length given to OCB")
+	}
+	if len(ciphertext) < o.tagSize {
+		return nil, errOpen
+	}
+	tag := ciphertext[len(ciphertext)-o.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-o.tagSize]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	var expectedTag [BlockSize]byte
+	o.crypt(out, ciphertext, nonce, &expectedTag, true)
+	o.hash(&expectedTag, additionalData)
+	if subtle.ConstantTimeCompare(expectedTag[:o.tagSize], tag) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// initialOffset computes Offset_0 from the nonce.
+func (o *ocb) initialOffset(offset *[BlockSize]byte, nonce []byte) {
+	var n [BlockSize]byte
+	n[0] = byte((o.tagSize * 8 % 128) << 1)
+	n[BlockSize-1-len(nonce)] |= 1
+	copy(n[BlockSize-len(nonce):], nonce)
+	bottom := uint(n[BlockSize-1] & 0x3f)
+	n[BlockSize-1] &= 0xc0
+
+	var stretch [BlockSize + 8]byte
+	o.b.Encrypt(stretch[:BlockSize], n[:])
+	for i := 0; i < 8; i++ {
+		stretch[BlockSize+i] = stretch[i] ^ stretch[i+1]
+	}
+
+	byteShift, bitShift := bottom/8, bottom%8
+	for i := uint(0); i < BlockSize; i++ {
+		offset[i] = stretch[i+byteShift] << bitShift
+		if bitShift > 0 {
+			offset[i] |= stretch[i+byteShift+1] >> (8 - bitShift)
+		}
+	}
+}
+
+// crypt encrypts or decrypts in into out and leaves in tag
+// the value ENCIPHER(K, Checksum ^ Offset ^ L_$), without HASH(K, A).
+// ocbChecksum xors the full blocks of plain into checksum.
+func ocbChecksum(checksum *[BlockSize]byte, plain []byte) {
+	for i := 0; i < len(plain); i += BlockSize {
+		xorBytes(checksum[:], checksum[:], plain[i:i+BlockSize])
+	}
+}
+
+func (o *ocb) crypt(out, in, nonce []byte, tag *[BlockSize]byte, decrypt bool) {
+	var offset, checksum [BlockSize]byte
+	o.initialOffset(&offset, nonce)
+
+	var offsets, buf [ocbBatch * BlockSize]byte
+	index := uint64(1)
+	for len(in) >= BlockSize {
+		n := len(in) / BlockSize
+		if n > ocbBatch {
+			n = ocbBatch
+		}
+		size := n * BlockSize
+		for i := 0; i < n; i++ {
+			xorBytes(offset[:], offset[:], o.l[bits.TrailingZeros64(index)][:])
+			copy(offsets[i*BlockSize:], offset[:])
+			index++
+		}
+		xorBytes(buf[:size], in[:size], offsets[:size])
+		if decrypt {
+			decryptBlocksWith(o.b, buf[:size], buf[:size])
+		} else {
+			// The checksum is taken before out is written:
+			// in and out may be the same memory.
+			ocbChecksum(&checksum, in[:size])
+			encryptBlocksWith(o.b, buf[:size], buf[:size])
+		}
+		xorBytes(out[:size], buf[:size], offsets[:size])
+		if decrypt {
+			ocbChecksum(&checksum, out[:size])
+		}
+		in, out = in[size:], out[size:]
+	}
+
+	if len(in) > 0 {
+		xorBytes(offset[:], offset[:], o.lStar[:])
+		var pad [BlockSize]byte
+		o.b.Encrypt(pad[:], offset[:])
+		if !decrypt {
+			xorBytes(checksum[:], checksum[:], in)
+		}
+		xorBytes(out, in, pad[:])
+		if decrypt {
+			xorBytes(checksum[:], checksum[:], out[:len(in)])
+		}
+		checksum[len(in)] ^= 0x80
+	}
+
+	xorBytes(tag[:], checksum[:], offset[:])
+	xorBytes(tag[:], tag[:], o.lDollar[:])
+	o.b.Encrypt(tag[:], tag[:])
+}
+
+// hash xors HASH(K, A) into sum.
+func (o *ocb) hash(sum *[BlockSize]byte, a []byte) {
+	var offset, block [BlockSize]byte
+	index := uint64(1)
+	for len(a) >= BlockSize {
+		xorBytes(offset[:], offset[:], o.l[bits.TrailingZeros64(index)][:])
+		xorBytes(block[:], a[:BlockSize], offset[:])
+		o.b.Encrypt(block[:], block[:])
+		xorBytes(sum[:], sum[:], block[:])
+		a = a[BlockSize:]
+		index++
+	}
+	if len(a) > 0 {
+		xorBytes(offset[:], offset[:], o.lStar[:])
+		block = [BlockSize]byte{}
+		copy(block[:], a)
+		block[len(a)] = 0x80
+		xorBytes(block[:], block[:], offset[:])
+		o.b.Encrypt(block[:], block[:])
+		xorBytes(sum[:], sum[:], block[:])
+	}
+}
@@ -0,0 +1,147 @@
+/*
+	ocb_test.go:  Unit tests of the OCB3 mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+// Sample results of RFC 7253 appendix A (AES-128, TAGLEN 128 and 96).
+func TestOCBWithAES(t *testing.T) {
+	tests := []struct {
+		key, nonce, aad, plainText, cipherText string
+		tagSize                                int
+	}{
+		{
+			"000102030405060708090a0b0c0d0e0f", "bbaa99887766554433221100",
+			"", "",
+			"785407bfffc8ad9edcc5520ac9111ee6", 16,
+		},
+		{
+			"000102030405060708090a0b0c0d0e0f", "bbaa99887766554433221101",
+			"0001020304050607", "0001020304050607",
+			"6820b3657b6f615a5725bda0d3b4eb3a257c9af1f8f03009", 16,
+		},
+		{
+			"000102030405060708090a0b0c0d0e0f", "bbaa99887766554433221102",
+			"0001020304050607", "",
+			"81017f8203f081277152fade694a0a00", 16,
+		},
+		{
+			"000102030405060708090a0b0c0d0e0f", "bbaa99887766554433221103",
+			"", "0001020304050607",
+			"45dd69f8f5aae72414054cd1f35d82760b2cd00d2f99bfa9", 16,
+		},
+		{
+			"000102030405060708090a0b0c0d0e0f", "bbaa99887766554433221104",
+			"000102030405060708090a0b0c0d0e0f", "000102030405060708090a0b0c0d0e0f",
+			"571d535b60b277188be5147170a9a22c3ad7a4ff3835b8c5701c1ccec8fc3358", 16,
+		},
+		{
+			"0f0e0d0c0b0a09080706050403020100", "bbaa9988776655443322110d",
+			"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021222324252627",
+			"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021222324252627",
+			"1792a4e31e0755fb03e31b22116e6c2ddf9efd6e33d536f1a0124b0a55bae884ed93481529c76b6ad0c515f4d1cdd4fdac4f02aa", 12,
+		},
+	}
+	for i, tt := range tests {
+		block, _ := aes.NewCipher(mustHex(t, tt.key))
+		aead, err := NewOCBWithSizes(block, len(tt.nonce)/2, tt.tagSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce, aad, plainText := mustHex(t, tt.nonce), mustHex(t, tt.aad), mustHex(t, tt.plainText)
+		expected := mustHex(t, tt.cipherText)
+
+		output := aead.Seal(nil, nonce, plainText, aad)
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d: Seal. Is %x, should: %x", i, output, expected)
+		}
+		decrypted, err := aead.Open(nil, nonce, expected, aad)
+		if err != nil || !bytes.Equal(decrypted, plainText) {
+			t.Errorf("%d: Open. Is (%x, %v), should: %x", i, decrypted, err, plainText)
+		}
+	}
+}
+
+func TestOCB(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+
+	for _, sizes := range [][2]int{{12, 16}, {1, 16}, {15, 16}, {12, 8}, {7, 12}} {
+		aead, err := NewOCBWithSizes(block, sizes[0], sizes[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce := iv[:sizes[0]]
+		for _, n := range []int{0, 1, 15, 16, 17, 128, 129, 300} {
+			plainText := testMessage(n)
+			aad := testMessage(n / 3)
+			sealed := aead.Seal(nil, nonce, plainText, aad)
+			if len(sealed) != n+sizes[1] {
+				t.Fatalf("Sealed length. Is %d, should: %d", len(sealed), n+sizes[1])
+			}
+			opened, err := aead.Open(nil, nonce, sealed, aad)
+			if err != nil || !bytes.Equal(opened, plainText) {
+				t.Errorf("Sizes %v, %d bytes. Is (%x, %v), should: %x", sizes, n, opened, err, plainText)
+			}
+			sealed[0] ^= 0x80
+			if _, err := aead.Open(nil, nonce, sealed, aad); err != errOpen {
+				t.Errorf("Sizes %v, %d bytes. Tampered message. Error is %v, should: %v", sizes, n, err, errOpen)
+			}
+		}
+	}
+}
+
+func TestOCBInPlace(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	aead, _ := NewOCB(block)
+	nonce := iv[:aead.NonceSize()]
+
+	for _, n := range []int{5, 16, 37, 200} {
+		plainText := testMessage(n)
+		expected := aead.Seal(nil, nonce, plainText, nil)
+
+		buf := make([]byte, n, n+aead.Overhead())
+		copy(buf, plainText)
+		sealed := aead.Seal(buf[:0], nonce, buf, nil)
+		if !bytes.Equal(sealed, expected) {
+			t.Errorf("%d bytes. Seal. Is %x, should: %x", n, sealed, expected)
+		}
+		opened, err := aead.Open(sealed[:0], nonce, sealed, nil)
+		if err != nil || !bytes.Equal(opened, plainText) {
+			t.Errorf("%d bytes. Open. Is (%x, %v), should: %x", n, opened, err, plainText)
+		}
+	}
+}
+
+func TestNewOCBErrors(t *testing.T) {
+	rawKey, _ := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	for _, sizes := range [][2]int{{0, 16}, {16, 16}, {12, 0}, {12, 17}} {
+		if _, err := NewOCBWithSizes(block, sizes[0], sizes[1]); err == nil {
+			t.Errorf("Sizes %v should be rejected", sizes)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+/*
+	ofb.go:  Serpent algorithm implementation in Go.
+
+	Output feedback (OFB) mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import "crypto/cipher"
+
+type ofb struct {
+	b    cipher.Block
+	out  [BlockSize]byte // last output block, also the next cipher input
+	used int             // keystream bytes consumed
+}
+
+// NewOFB returns an OFB stream for the block b starting with the
+// 16-byte iv. OFB encryption and decryption are the same operation.
+// The keystream depends only on the key and iv, so an iv must never
+// be reused with the same key.
+func NewOFB(b cipher.Block, iv []byte) (cipher.Stream, error) {
+	if err := checkFeedback(b, iv); err != nil {
+		return nil, err
+	}
+	x := &ofb{b: b, used: BlockSize}
+	copy(x.out[:], iv)
+	return x, nil
+}
+
+func (x *ofb) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("serpent: output smaller than input")
+	}
+	for len(src) > 0 {
+		if x.used == BlockSize {
+			x.b.Encrypt(x.out[:], x.out[:])
+			x.used = 0
+		}
+		n := xorBytes(dst, src, x.out[x.used:])
+		dst, src = dst[n:], src[n:]
+		x.used += n
+	}
+}
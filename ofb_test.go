@@ -0,0 +1,50 @@
+/*
+	ofb_test.go:  Unit tests of the OFB mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+)
+
+// Fixtures generated with libgcrypt (GCRY_CIPHER_MODE_OFB).
+func TestOFBVectors(t *testing.T) {
+	testStreamVectors(t, "ofb.txt", NewOFB, NewOFB)
+}
+
+func TestOFB(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	plainText := testMessage(100)
+
+	expected := make([]byte, len(plainText))
+	cipher.NewOFB(block, iv).XORKeyStream(expected, plainText)
+
+	stream, _ := NewOFB(block, iv)
+	out := make([]byte, len(plainText))
+	stream.XORKeyStream(out[:3], plainText[:3])
+	stream.XORKeyStream(out[3:50], plainText[3:50])
+	stream.XORKeyStream(out[50:], plainText[50:])
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Is %x, should: %x", out, expected)
+	}
+}
@@ -0,0 +1,194 @@
+/*
+	padding.go:  Serpent algorithm implementation in Go.
+
+	Block padding schemes: PKCS#7, ISO/IEC 7816-4, ANSI X9.23
+	and ISO 10126.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+// Package padding pads messages to a multiple of the block size.
+//
+// Unpad looks at the whole last block in constant time and returns
+// ErrPadding for every kind of bad padding, so that neither the time
+// taken nor the error tells which check failed.
+package padding
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"io"
+)
+
+// ErrPadding is returned by Unpad for input that is not correctly padded.
+var ErrPadding = errors.New("padding: invalid padding")
+
+// Scheme is a block padding scheme.
+type Scheme interface {
+	// Pad returns data followed by 1 to blockSize bytes of padding.
+	// It panics if blockSize is not between 1 and 255.
+	Pad(data []byte, blockSize int) []byte
+
+	// Unpad returns data without the padding. The result shares
+	// the memory of data.
+	Unpad(data []byte, blockSize int) ([]byte, error)
+}
+
+var (
+	// PKCS7 pads with n bytes of value n (RFC 5652).
+	PKCS7 Scheme = pkcs7{}
+	// ISO7816 pads with 0x80 followed by zeros (ISO/IEC 7816-4).
+	ISO7816 Scheme = iso7816{}
+	// X923 pads with zeros and a final byte n (ANSI X9.23).
+	X923 Scheme = x923{}
+	// ISO10126 pads with random bytes and a final byte n (ISO 10126).
+	ISO10126 Scheme = iso10126{random: rand.Reader}
+)
+
+// padLength returns the number of padding bytes for data.
+func padLength(data []byte, blockSize int) int {
+	if blockSize < 1 || blockSize > 255 {
+		panic("padding: invalid block size")
+	}
+	return blockSize - len(data)%blockSize
+}
+
+// lastBlock returns the last block of data, which must be a non-empty
+// multiple of blockSize. The length is public, so it is checked first.
+func lastBlock(data []byte, blockSize int) ([]byte, bool) {
+	if blockSize < 1 || blockSize > 255 {
+		panic("padding: invalid block size")
+	}
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, false
+	}
+	return data[len(data)-blockSize:], true
+}
+
+// checkLength returns 1 if the padding length n is between 1 and
+// blockSize.
+func checkLength(n, blockSize int) int {
+	return subtle.ConstantTimeLessOrEq(1, n) & subtle.ConstantTimeLessOrEq(n, blockSize)
+}
+
+// trailerUnpad unpads the schemes ending with the length byte.
+// Byte i of the last block counts as padding when blockSize-n <= i,
+// and each padding byte except the length must equal fill (or anything
+// if fill is negative).
+func trailerUnpad(data []byte, blockSize, fill int) ([]byte, error) {
+	block, ok := lastBlock(data, blockSize)
+	if !ok {
+		return nil, ErrPadding
+	}
+	n := int(block[blockSize-1])
+	good := checkLength(n, blockSize)
+	if fill >= 0 {
+		for i := 0; i < blockSize-1; i++ {
+			inPadding := subtle.ConstantTimeLessOrEq(blockSize-n, i)
+			match := subtle.ConstantTimeByteEq(block[i], byte(fill))
+			good &= match | (inPadding ^ 1)
+		}
+	}
+	if good != 1 {
+		return nil, ErrPadding
+	}
+	return data[:len(data)-n], nil
+}
+
+type pkcs7 struct{}
+
+func (pkcs7) Pad(data []byte, blockSize int) []byte {
+	n := padLength(data, blockSize)
+	out := make([]byte, len(data)+n)
+	copy(out, data)
+	for i := len(data); i < len(out); i++ {
+		out[i] = byte(n)
+	}
+	return out
+}
+
+func (pkcs7) Unpad(data []byte, blockSize int) ([]byte, error) {
+	// Every padding byte equals the length byte.
+	block, ok := lastBlock(data, blockSize)
+	if !ok {
+		return nil, ErrPadding
+	}
+	return trailerUnpad(data, blockSize, int(block[blockSize-1]))
+}
+
+type x923 struct{}
+
+func (x923) Pad(data []byte, blockSize int) []byte {
+	n := padLength(data, blockSize)
+	out := make([]byte, len(data)+n)
+	copy(out, data)
+	out[len(out)-1] = byte(n)
+	return out
+}
+
+func (x923) Unpad(data []byte, blockSize int) ([]byte, error) {
+	return trailerUnpad(data, blockSize, 0)
+}
+
+type iso10126 struct {
+	random io.Reader
+}
+
+func (s iso10126) Pad(data []byte, blockSize int) []byte {
+	n := padLength(data, blockSize)
+	out := make([]byte, len(data)+n)
+	copy(out, data)
+	if _, err := io.ReadFull(s.random, out[len(data):len(out)-1]); err != nil {
+		panic("padding: reading random bytes: " + err.Error())
+	}
+	out[len(out)-1] = byte(n)
+	return out
+}
+
+func (iso10126) Unpad(data []byte, blockSize int) ([]byte, error) {
+	return trailerUnpad(data, blockSize, -1)
+}
+
+type iso7816 struct{}
+
+func (iso7816) Pad(data []byte, blockSize int) []byte {
+	n := padLength(data, blockSize)
+	out := make([]byte, len(data)+n)
+	copy(out, data)
+	out[len(data)] = 0x80
+	return out
+}
+
+func (iso7816) Unpad(data []byte, blockSize int) ([]byte, error) {
+	block, ok := lastBlock(data, blockSize)
+	if !ok {
+		return nil, ErrPadding
+	}
+	// The last non-zero byte of the block must be the 0x80 marker.
+	found, marker, last := 0, 0, 0
+	for i := 0; i < blockSize; i++ {
+		nonZero := subtle.ConstantTimeByteEq(block[i], 0) ^ 1
+		found |= nonZero
+		marker = subtle.ConstantTimeSelect(nonZero, subtle.ConstantTimeByteEq(block[i], 0x80), marker)
+		last = subtle.ConstantTimeSelect(nonZero, i, last)
+	}
+	if found&marker != 1 {
+		return nil, ErrPadding
+	}
+	return data[:len(data)-blockSize+last], nil
+}
@@ -0,0 +1,124 @@
+/*
+	padding_test.go:  Unit tests of the padding schemes.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package padding
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestPad(t *testing.T) {
+	tests := []struct {
+		scheme Scheme
+		data   string
+		padded string
+	}{
+		{PKCS7, "", "0808080808080808"},
+		{PKCS7, "0102030405", "0102030405030303"},
+		{PKCS7, "0102030405060708", "01020304050607080808080808080808"},
+		{ISO7816, "0102030405", "0102030405800000"},
+		{ISO7816, "01020304050607", "0102030405060780"},
+		{ISO7816, "0102030405060708", "01020304050607088000000000000000"},
+		{X923, "0102030405", "0102030405000003"},
+		{X923, "0102030405060708", "01020304050607080000000000000008"},
+	}
+	for _, tt := range tests {
+		expected := mustHex(t, tt.padded)
+		padded := tt.scheme.Pad(mustHex(t, tt.data), 8)
+		if !bytes.Equal(padded, expected) {
+			t.Errorf("%T %s. Is %x, should: %x", tt.scheme, tt.data, padded, expected)
+		}
+	}
+
+	// ISO 10126 padding bytes are random, only the length is fixed.
+	padded := ISO10126.Pad(mustHex(t, "0102030405"), 8)
+	if len(padded) != 8 || padded[7] != 3 || !bytes.Equal(padded[:5], mustHex(t, "0102030405")) {
+		t.Errorf("ISO10126. Is %x", padded)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, scheme := range []Scheme{PKCS7, ISO7816, X923, ISO10126} {
+		for _, blockSize := range []int{1, 8, 16, 255} {
+			for n := 0; n <= 2*blockSize+1; n++ {
+				data := bytes.Repeat([]byte{0xa5}, n)
+				padded := scheme.Pad(data, blockSize)
+				if len(padded)%blockSize != 0 || len(padded) <= n {
+					t.Fatalf("%T %d/%d: padded length %d", scheme, n, blockSize, len(padded))
+				}
+				unpadded, err := scheme.Unpad(padded, blockSize)
+				if err != nil || !bytes.Equal(unpadded, data) {
+					t.Errorf("%T %d/%d. Is (%x, %v), should: %x", scheme, n, blockSize, unpadded, err, data)
+				}
+			}
+		}
+	}
+}
+
+func TestUnpadInvalid(t *testing.T) {
+	tests := []struct {
+		scheme Scheme
+		data   string
+	}{
+		{PKCS7, ""},
+		{PKCS7, "01020304050607"},
+		{PKCS7, "0102030405060700"},
+		{PKCS7, "0102030405060709"},
+		{PKCS7, "0102030405030203"},
+		{PKCS7, "0808080808080808" + "0102030405060710"},
+		{ISO7816, "0000000000000000"},
+		{ISO7816, "0102030405800001"},
+		{ISO7816, "0102030405060708"},
+		{ISO7816, "0102030405810000"},
+		{X923, "0102030405000000"},
+		{X923, "0102030405010003"},
+		{X923, "0102030405060709"},
+		{ISO10126, "0102030405060700"},
+		{ISO10126, "0102030405060709"},
+	}
+	for _, tt := range tests {
+		if _, err := tt.scheme.Unpad(mustHex(t, tt.data), 8); err != ErrPadding {
+			t.Errorf("%T %s. Is %v, should: %v", tt.scheme, tt.data, err, ErrPadding)
+		}
+	}
+}
+
+func TestInvalidBlockSize(t *testing.T) {
+	for _, blockSize := range []int{0, 256} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("block size %d accepted", blockSize)
+				}
+			}()
+			PKCS7.Pad(nil, blockSize)
+		}()
+	}
+}
@@ -0,0 +1,65 @@
+/*
+	pcbc.go:  Serpent algorithm implementation in Go.
+
+	Propagating cipher block chaining (PCBC) mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import "crypto/cipher"
+
+// EncryptPCBC encrypts whole blocks of src into dst in PCBC mode:
+//
+//	C[i] = E(P[i] ^ P[i-1] ^ C[i-1]),  P[0] ^ C[0] = iv
+//
+// dst and src may be the same slice. Note that swapping two adjacent
+// ciphertext blocks only garbles those two blocks.
+func EncryptPCBC(b cipher.Block, dst, src, iv []byte) error {
+	return pcbc(b, dst, src, iv, false)
+}
+
+// DecryptPCBC decrypts whole blocks of src into dst in PCBC mode:
+//
+//	P[i] = D(C[i]) ^ P[i-1] ^ C[i-1]
+func DecryptPCBC(b cipher.Block, dst, src, iv []byte) error {
+	return pcbc(b, dst, src, iv, true)
+}
+
+func pcbc(b cipher.Block, dst, src, iv []byte, decrypt bool) error {
+	if err := checkBlocks(b, dst, src); err != nil {
+		return err
+	}
+	if len(iv) != BlockSize {
+		return Error(BAD_IV)
+	}
+	var chain, in, out [BlockSize]byte // chain = P[i-1] ^ C[i-1]
+	copy(chain[:], iv)
+	for i := 0; i < len(src); i += BlockSize {
+		copy(in[:], src[i:i+BlockSize])
+		if decrypt {
+			b.Decrypt(out[:], in[:])
+			xorBytes(out[:], out[:], chain[:])
+		} else {
+			xorBytes(out[:], in[:], chain[:])
+			b.Encrypt(out[:], out[:])
+		}
+		xorBytes(chain[:], in[:], out[:])
+		copy(dst[i:], out[:])
+	}
+	return nil
+}
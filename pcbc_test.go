@@ -0,0 +1,87 @@
+/*
+	pcbc_test.go:  Unit tests of the PCBC mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPCBC(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	plainText := testMessage(5 * BlockSize)
+
+	// C[i] = E(P[i] ^ P[i-1] ^ C[i-1]) written out block by block.
+	expected := make([]byte, len(plainText))
+	chain := append([]byte(nil), iv...)
+	for i := 0; i < len(plainText); i += BlockSize {
+		p := plainText[i : i+BlockSize]
+		c := expected[i : i+BlockSize]
+		xorBytes(c, p, chain)
+		block.Encrypt(c, c)
+		xorBytes(chain, p, c)
+	}
+
+	out := append([]byte(nil), plainText...)
+	if err := EncryptPCBC(block, out, out, iv); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Is %x, should: %x", out, expected)
+	}
+	if err := DecryptPCBC(block, out, out, iv); err != nil || !bytes.Equal(out, plainText) {
+		t.Errorf("Decrypted. Is (%x, %v), should: %x", out, err, plainText)
+	}
+	if err := EncryptPCBC(block, out, out, iv[:8]); err != Error(BAD_IV) {
+		t.Errorf("Short IV. Error is %v, should: %v", err, Error(BAD_IV))
+	}
+}
+
+// A changed ciphertext block garbles every following plaintext block,
+// while swapping two adjacent blocks garbles only those two.
+func TestPCBCErrorPropagation(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	plainText := testMessage(8 * BlockSize)
+	cipherText := make([]byte, len(plainText))
+	EncryptPCBC(block, cipherText, plainText, iv)
+
+	changed := append([]byte(nil), cipherText...)
+	changed[3*BlockSize+5] ^= 0x10
+	out := make([]byte, len(plainText))
+	DecryptPCBC(block, out, changed, iv)
+	for i, garbled := range garbledBlocks(out, plainText) {
+		if garbled != (i >= 3) {
+			t.Errorf("Changed block, block %d garbled: %v, should: %v", i, garbled, i >= 3)
+		}
+	}
+
+	swapped := append([]byte(nil), cipherText...)
+	copy(swapped[3*BlockSize:], cipherText[4*BlockSize:5*BlockSize])
+	copy(swapped[4*BlockSize:], cipherText[3*BlockSize:4*BlockSize])
+	DecryptPCBC(block, out, swapped, iv)
+	for i, garbled := range garbledBlocks(out, plainText) {
+		if garbled != (i == 3 || i == 4) {
+			t.Errorf("Swapped blocks, block %d garbled: %v, should: %v", i, garbled, i == 3 || i == 4)
+		}
+	}
+}
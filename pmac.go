@@ -0,0 +1,188 @@
+/*
+	pmac.go:  Serpent algorithm implementation in Go.
+
+	PMAC1, the parallelizable message authentication code of Black
+	and Rogaway.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"math/bits"
+)
+
+const pmacBatch = 8
+
+// PMAC computes the PMAC of the data written to it and implements
+// hash.Hash. Besides the sequential Write, a message can be split into
+// runs of whole blocks whose partial sums are computed independently,
+// e.g. on separate goroutines, and then combined with Finish.
+type PMAC struct {
+	b      cipher.Block
+	l      [64][BlockSize]byte // L(i) = L·x^i
+	lInv   [BlockSize]byte     // L·x^-1
+	offset [BlockSize]byte
+	sigma  [BlockSize]byte
+	index  uint64          // blocks added to sigma
+	buf    [BlockSize]byte // pending input, always kept until more data comes
+	n      int             // bytes in buf
+}
+
+// NewPMAC returns a PMAC using the block b.
+func NewPMAC(b cipher.Block) (*PMAC, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, errors.New("serpent: PMAC requires a 128-bit block cipher")
+	}
+	m := &PMAC{b: b}
+	b.Encrypt(m.l[0][:], m.l[0][:])
+	for i := 1; i < len(m.l); i++ {
+		double(&m.l[i], &m.l[i-1])
+	}
+	halve(&m.lInv, &m.l[0])
+	return m, nil
+}
+
+// halve divides by x in GF(2^128), the inverse of double.
+func halve(out, in *[BlockSize]byte) {
+	carry := in[BlockSize-1] & 1
+	for i := BlockSize - 1; i > 0; i-- {
+		out[i] = (in[i] >> 1) | (in[i-1] << 7)
+	}
+	out[0] = in[0] >> 1
+	out[0] ^= 0x80 & -carry
+	out[BlockSize-1] ^= 0x43 & -carry
+}
+
+// Size returns the tag size in bytes.
+func (m *PMAC) Size() int { return BlockSize }
+
+// BlockSize returns the block size of the underlying cipher.
+func (m *PMAC) BlockSize() int { return BlockSize }
+
+// Reset clears the state, the key dependent values are kept.
+func (m *PMAC) Reset() {
+	m.offset = [BlockSize]byte{}
+	m.sigma = [BlockSize]byte{}
+	m.index = 0
+	m.n = 0
+}
+
+// Write adds more data. It never returns an error.
+func (m *PMAC) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		if m.n == BlockSize {
+			m.index = m.sumBlocks(&m.sigma, &m.offset, m.index, m.buf[:])
+			m.n = 0
+		}
+		if m.n == 0 && len(p) > BlockSize {
+			// Whole blocks go straight in, except the last one.
+			k := (len(p) - 1) / BlockSize * BlockSize
+			m.index = m.sumBlocks(&m.sigma, &m.offset, m.index, p[:k])
+			p = p[k:]
+			continue
+		}
+		k := copy(m.buf[m.n:], p)
+		m.n += k
+		p = p[k:]
+	}
+	return written, nil
+}
+
+// Sum appends the tag of the data written so far to in.
+// It does not change the state.
+func (m *PMAC) Sum(in []byte) []byte {
+	return m.finish(in, m.sigma, m.buf[:m.n])
+}
+
+// Verify reports whether tag is the tag of the data written so far.
+// The comparison is done in constant time.
+func (m *PMAC) Verify(tag []byte) bool {
+	var sum [BlockSize]byte
+	return subtle.ConstantTimeCompare(m.Sum(sum[:0]), tag) == 1
+}
+
+// Partial returns the partial sum of blocks, a run of whole blocks
+// starting at block number first (counting from zero) of the message.
+// The last block of the message must not be included. Partial does not
+// use the state set by Write and is safe for concurrent use.
+func (m *PMAC) Partial(first uint64, blocks []byte) [BlockSize]byte {
+	if len(blocks)%BlockSize != 0 {
+		panic("serpent: PMAC partial input not full blocks")
+	}
+	// The offset of block i is the sum of L(j) over the bits j
+	// set in the Gray code of i.
+	var offset, sigma [BlockSize]byte
+	for g := first ^ first>>1; g != 0; g &= g - 1 {
+		xorBytes(offset[:], offset[:], m.l[bits.TrailingZeros64(g)][:])
+	}
+	m.sumBlocks(&sigma, &offset, first, blocks)
+	return sigma
+}
+
+// Finish appends to in the tag of the message made of the blocks whose
+// partial sums are given, followed by the last block (1 to 16 bytes,
+// or empty for an empty message).
+func (m *PMAC) Finish(in, last []byte, sums ...[BlockSize]byte) []byte {
+	if len(last) > BlockSize {
+		panic("serpent: PMAC last block too long")
+	}
+	var sigma [BlockSize]byte
+	for i := range sums {
+		xorBytes(sigma[:], sigma[:], sums[i][:])
+	}
+	return m.finish(in, sigma, last)
+}
+
+// sumBlocks adds whole blocks to sigma, starting after block number
+// index whose offset is given, and returns the new block count.
+func (m *PMAC) sumBlocks(sigma, offset *[BlockSize]byte, index uint64, blocks []byte) uint64 {
+	var buf [pmacBatch * BlockSize]byte
+	for len(blocks) > 0 {
+		n := len(blocks) / BlockSize
+		if n > pmacBatch {
+			n = pmacBatch
+		}
+		size := n * BlockSize
+		for i := 0; i < size; i += BlockSize {
+			index++
+			xorBytes(offset[:], offset[:], m.l[bits.TrailingZeros64(index)][:])
+			xorBytes(buf[i:], blocks[i:i+BlockSize], offset[:])
+		}
+		encryptBlocksWith(m.b, buf[:size], buf[:size])
+		for i := 0; i < size; i += BlockSize {
+			xorBytes(sigma[:], sigma[:], buf[i:i+BlockSize])
+		}
+		blocks = blocks[size:]
+	}
+	return index
+}
+
+func (m *PMAC) finish(in []byte, sigma [BlockSize]byte, last []byte) []byte {
+	xorBytes(sigma[:], sigma[:], last)
+	if len(last) == BlockSize {
+		xorBytes(sigma[:], sigma[:], m.lInv[:])
+	} else {
+		sigma[len(last)] ^= 0x80
+	}
+	m.b.Encrypt(sigma[:], sigma[:])
+	return append(in, sigma[:]...)
+}
@@ -0,0 +1,136 @@
+/*
+	pmac_test.go:  Unit tests of PMAC.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"hash"
+	"sync"
+	"testing"
+)
+
+var _ hash.Hash = (*PMAC)(nil)
+
+// PMAC-AES-128 test vectors of Rogaway's reference implementation.
+func TestPMACWithAES(t *testing.T) {
+	b, _ := aes.NewCipher(mustHex(t, "000102030405060708090a0b0c0d0e0f"))
+	m, err := NewPMAC(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counting := make([]byte, 34)
+	for i := range counting {
+		counting[i] = byte(i)
+	}
+	tests := []struct {
+		msg []byte
+		tag string
+	}{
+		{counting[:0], "4399572cd6ea5341b8d35876a7098af7"},
+		{counting[:3], "256ba5193c1b991b4df0c51f388a9e27"},
+		{counting[:16], "ebbd822fa458daf6dfdad7c27da76338"},
+		{counting[:20], "0412ca150bbf79058d8c75a58c993f55"},
+		{counting[:32], "e97ac04e9e5e3399ce5355cd7407bc75"},
+		{counting[:34], "5cba7d5eb24f7c86ccc54604e53d5512"},
+		{make([]byte, 1000), "c2c9fa1d9985f6f0d2aff915a0e8d910"},
+	}
+	for _, tt := range tests {
+		expected := mustHex(t, tt.tag)
+		m.Reset()
+		m.Write(tt.msg)
+		if tag := m.Sum(nil); !bytes.Equal(tag, expected) {
+			t.Errorf("%d bytes. Is %x, should: %x", len(tt.msg), tag, expected)
+		}
+	}
+}
+
+func TestPMACHalve(t *testing.T) {
+	for _, s := range []string{"00000000000000000000000000000001", "80000000000000000000000000000000", "0123456789abcdeffedcba9876543210"} {
+		var in, out, back [BlockSize]byte
+		copy(in[:], mustHex(t, s))
+		halve(&out, &in)
+		double(&back, &out)
+		if back != in {
+			t.Errorf("double(halve(%x)). Is %x, should: %x", in, back, in)
+		}
+	}
+}
+
+func TestPMAC(t *testing.T) {
+	key, _ := testKeyAndIV()
+	b, _ := NewCipher(key)
+	m, _ := NewPMAC(b)
+
+	for _, size := range []int{0, 1, 16, 17, 100, 128, 1000, 4096 + 5} {
+		msg := testMessage(size)
+		m.Reset()
+		m.Write(msg)
+		expected := m.Sum(nil)
+
+		// Writes of any size give the same tag.
+		for _, step := range []int{1, 15, 16, 33} {
+			m.Reset()
+			for p := msg; len(p) > 0; {
+				n := step
+				if n > len(p) {
+					n = len(p)
+				}
+				m.Write(p[:n])
+				p = p[n:]
+			}
+			if tag := m.Sum(nil); !bytes.Equal(tag, expected) {
+				t.Errorf("%d bytes, step %d: Is %x, should: %x", size, step, tag, expected)
+			}
+		}
+		if !m.Verify(expected) {
+			t.Errorf("%d bytes: valid tag rejected", size)
+		}
+
+		// Partial sums over chunks on separate goroutines.
+		lastLen := size % BlockSize
+		if lastLen == 0 && size > 0 {
+			lastLen = BlockSize
+		}
+		body, last := msg[:size-lastLen], msg[size-lastLen:]
+		for _, chunk := range []int{1, 3, 8} {
+			chunkSize := chunk * BlockSize
+			sums := make([][BlockSize]byte, (len(body)+chunkSize-1)/chunkSize)
+			var wg sync.WaitGroup
+			for i := range sums {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					start := i * chunkSize
+					end := start + chunkSize
+					if end > len(body) {
+						end = len(body)
+					}
+					sums[i] = m.Partial(uint64(start/BlockSize), body[start:end])
+				}(i)
+			}
+			wg.Wait()
+			if tag := m.Finish(nil, last, sums...); !bytes.Equal(tag, expected) {
+				t.Errorf("%d bytes, chunks of %d blocks: Is %x, should: %x", size, chunk, tag, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+/*
+	poly1305.go:  Serpent algorithm implementation in Go.
+
+	The Poly1305 one-time authenticator (RFC 8439) and Poly1305-Serpent,
+	the analogue of Bernstein's Poly1305-AES.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"math/bits"
+)
+
+// Sizes of the Poly1305 one-time key and of the tag.
+const (
+	Poly1305KeySize = 32
+	Poly1305TagSize = 16
+)
+
+// Poly1305Sum writes to out the Poly1305 tag of msg under the one-time
+// key r || s. A key must never be used for more than one message.
+func Poly1305Sum(out *[Poly1305TagSize]byte, msg []byte, key *[Poly1305KeySize]byte) {
+	p := newPoly1305(key[:16], key[16:])
+	p.Write(msg)
+	p.Sum(out)
+}
+
+// Poly1305Verify reports in constant time whether tag is the Poly1305
+// tag of msg under the one-time key.
+func Poly1305Verify(tag *[Poly1305TagSize]byte, msg []byte, key *[Poly1305KeySize]byte) bool {
+	var sum [Poly1305TagSize]byte
+	Poly1305Sum(&sum, msg, key)
+	return subtle.ConstantTimeCompare(sum[:], tag[:]) == 1
+}
+
+// poly1305 keeps the accumulator h as a 130-bit number in h0, h1, h2,
+// only partially reduced modulo 2^130 - 5 between blocks.
+type poly1305 struct {
+	r0, r1     uint64
+	s0, s1     uint64
+	h0, h1, h2 uint64
+	buf        [16]byte
+	n          int
+}
+
+func newPoly1305(r, s []byte) *poly1305 {
+	return &poly1305{
+		r0: binary.LittleEndian.Uint64(r[0:]) & 0x0ffffffc0fffffff,
+		r1: binary.LittleEndian.Uint64(r[8:]) & 0x0ffffffc0ffffffc,
+		s0: binary.LittleEndian.Uint64(s[0:]),
+		s1: binary.LittleEndian.Uint64(s[8:]),
+	}
+}
+
+func (p *poly1305) Write(msg []byte) (int, error) {
+	written := len(msg)
+	if p.n > 0 {
+		k := copy(p.buf[p.n:], msg)
+		p.n += k
+		msg = msg[k:]
+		if p.n < len(p.buf) {
+			return written, nil
+		}
+		p.blocks(p.buf[:], 1)
+		p.n = 0
+	}
+	if full := len(msg) &^ 15; full > 0 {
+		p.blocks(msg[:full], 1)
+		msg = msg[full:]
+	}
+	p.n = copy(p.buf[:], msg)
+	return written, nil
+}
+
+// pad feeds zeros up to the next 16-byte boundary.
+func (p *poly1305) pad() {
+	if p.n > 0 {
+		var zeros [16]byte
+		p.Write(zeros[p.n:])
+	}
+}
+
+// Sum writes the tag to out. It does not change the state.
+func (p *poly1305) Sum(out *[Poly1305TagSize]byte) {
+	h0, h1, h2 := p.h0, p.h1, p.h2
+	if p.n > 0 {
+		// The last partial block gets a single 1 bit after the data.
+		var last [16]byte
+		copy(last[:], p.buf[:p.n])
+		last[p.n] = 1
+		q := *p
+		q.blocks(last[:], 0)
+		h0, h1, h2 = q.h0, q.h1, q.h2
+	}
+
+	// Subtract 2^130 - 5 once if h is not smaller, which fully reduces h.
+	t0, b := bits.Sub64(h0, 0xfffffffffffffffb, 0)
+	t1, b := bits.Sub64(h1, 0xffffffffffffffff, b)
+	_, b = bits.Sub64(h2, 3, b)
+	mask := b - 1 // all ones when there was no borrow
+	h0 = h0&^mask | t0&mask
+	h1 = h1&^mask | t1&mask
+
+	h0, c := bits.Add64(h0, p.s0, 0)
+	h1, _ = bits.Add64(h1, p.s1, c)
+	binary.LittleEndian.PutUint64(out[0:], h0)
+	binary.LittleEndian.PutUint64(out[8:], h1)
+}
+
+// blocks processes whole 16-byte blocks, adding hibit at 2^128 of each.
+func (p *poly1305) blocks(msg []byte, hibit uint64) {
+	h0, h1, h2 := p.h0, p.h1, p.h2
+	r0, r1 := p.r0, p.r1
+	for len(msg) >= 16 {
+		var c uint64
+		h0, c = bits.Add64(h0, binary.LittleEndian.Uint64(msg[0:]), 0)
+		h1, c = bits.Add64(h1, binary.LittleEndian.Uint64(msg[8:]), c)
+		h2 += c + hibit
+
+		// h * r. h2 is at most 7 and the clamped top bits of r0 and r1
+		// keep the column sums from overflowing.
+		h0r0hi, h0r0lo := bits.Mul64(h0, r0)
+		h1r0hi, h1r0lo := bits.Mul64(h1, r0)
+		h0r1hi, h0r1lo := bits.Mul64(h0, r1)
+		h1r1hi, h1r1lo := bits.Mul64(h1, r1)
+		h2r0 := h2 * r0
+		h2r1 := h2 * r1
+
+		m1lo, c := bits.Add64(h1r0lo, h0r1lo, 0)
+		m1hi, _ := bits.Add64(h1r0hi, h0r1hi, c)
+		m2lo, c := bits.Add64(h2r0, h1r1lo, 0)
+		m2hi, _ := bits.Add64(0, h1r1hi, c)
+
+		t0 := h0r0lo
+		t1, c := bits.Add64(m1lo, h0r0hi, 0)
+		t2, c := bits.Add64(m2lo, m1hi, c)
+		t3, _ := bits.Add64(h2r1, m2hi, c)
+
+		// Reduce: the part above 2^130 is multiplied by 5 = 4 + 1
+		// and added to the low 130 bits.
+		h0, h1, h2 = t0, t1, t2&3
+		cc0, cc1 := t2&^3, t3
+		h0, c = bits.Add64(h0, cc0, 0)
+		h1, c = bits.Add64(h1, cc1, c)
+		h2 += c
+		cc0, cc1 = cc0>>2|cc1<<62, cc1>>2
+		h0, c = bits.Add64(h0, cc0, 0)
+		h1, c = bits.Add64(h1, cc1, c)
+		h2 += c
+
+		msg = msg[16:]
+	}
+	p.h0, p.h1, p.h2 = h0, h1, h2
+}
+
+// Poly1305Serpent is Poly1305 with s computed as the Serpent encryption
+// of a per-message nonce, like Poly1305-AES. The key r may be used for
+// many messages as long as no nonce is ever repeated.
+type Poly1305Serpent struct {
+	b cipher.Block
+	r [16]byte
+}
+
+// NewPoly1305Serpent takes a Serpent key k (16, 24 or 32 bytes)
+// followed by the 16-byte Poly1305 key r.
+func NewPoly1305Serpent(key []byte) (*Poly1305Serpent, error) {
+	return newPoly1305Serpent(NewCipher, key)
+}
+
+func newPoly1305Serpent(newCipher func([]byte) (cipher.Block, error), key []byte) (*Poly1305Serpent, error) {
+	if len(key) < 16 {
+		return nil, KeySizeError(len(key))
+	}
+	b, err := newCipher(key[:len(key)-16])
+	if err != nil {
+		return nil, err
+	}
+	if b.BlockSize() != BlockSize {
+		return nil, KeySizeError(len(key))
+	}
+	p := &Poly1305Serpent{b: b}
+	copy(p.r[:], key[len(key)-16:])
+	return p, nil
+}
+
+// NonceSize returns the size of the nonces taken by Sum and Verify.
+func (p *Poly1305Serpent) NonceSize() int { return BlockSize }
+
+// Sum appends the tag of msg under the 16-byte nonce to dst.
+func (p *Poly1305Serpent) Sum(dst, nonce, msg []byte) []byte {
+	if len(nonce) != BlockSize {
+		panic("serpent: incorrect nonce length given to Poly1305-Serpent")
+	}
+	var s [BlockSize]byte
+	p.b.Encrypt(s[:], nonce)
+	var tag [Poly1305TagSize]byte
+	m := newPoly1305(p.r[:], s[:])
+	m.Write(msg)
+	m.Sum(&tag)
+	return append(dst, tag[:]...)
+}
+
+// Verify reports in constant time whether tag is the tag of msg
+// under the nonce.
+func (p *Poly1305Serpent) Verify(tag, nonce, msg []byte) bool {
+	var sum [Poly1305TagSize]byte
+	return subtle.ConstantTimeCompare(p.Sum(sum[:0], nonce, msg), tag) == 1
+}
@@ -0,0 +1,240 @@
+/*
+	poly1305_test.go:  Unit tests of Poly1305 and Poly1305-Serpent.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Test vectors of RFC 8439 section 2.5.2 and appendix A.3.
+func TestPoly1305(t *testing.T) {
+	tests := []struct {
+		key string
+		msg string
+		tag string
+	}{
+		{
+			"85d6be7857556d337f4452fe42d506a80103808afb0db2fd4abff6af4149f51b",
+			"43727970746f6772617068696320466f72756d2052657365617263682047726f7570",
+			"a8061dc1305136c6c22b8baf0c0127a9",
+		},
+		{
+			strings.Repeat("00", 32),
+			strings.Repeat("00", 64),
+			"00000000000000000000000000000000",
+		},
+		{
+			"02" + strings.Repeat("00", 31),
+			strings.Repeat("ff", 16),
+			"03000000000000000000000000000000",
+		},
+		{
+			"02" + strings.Repeat("00", 15) + strings.Repeat("ff", 16),
+			"02" + strings.Repeat("00", 15),
+			"03000000000000000000000000000000",
+		},
+		{
+			"01" + strings.Repeat("00", 31),
+			strings.Repeat("ff", 16) + "f0" + strings.Repeat("ff", 15) + "11" + strings.Repeat("00", 15),
+			"05000000000000000000000000000000",
+		},
+		{
+			"01" + strings.Repeat("00", 31),
+			strings.Repeat("ff", 16) + "fb" + strings.Repeat("fe", 15) + strings.Repeat("01", 16),
+			"00000000000000000000000000000000",
+		},
+		{
+			"02" + strings.Repeat("00", 31),
+			"fd" + strings.Repeat("ff", 15),
+			"faffffffffffffffffffffffffffffff",
+		},
+	}
+	for i, tt := range tests {
+		var key [Poly1305KeySize]byte
+		copy(key[:], mustHex(t, tt.key))
+		msg := mustHex(t, tt.msg)
+		expected := mustHex(t, tt.tag)
+
+		var tag [Poly1305TagSize]byte
+		Poly1305Sum(&tag, msg, &key)
+		if !bytes.Equal(tag[:], expected) {
+			t.Errorf("%d: Is %x, should: %x", i, tag, expected)
+		}
+		if !Poly1305Verify(&tag, msg, &key) {
+			t.Errorf("%d: valid tag rejected", i)
+		}
+		tag[0] ^= 1
+		if Poly1305Verify(&tag, msg, &key) {
+			t.Errorf("%d: modified tag accepted", i)
+		}
+	}
+}
+
+// poly1305Big is the textbook definition of Poly1305 with math/big.
+func poly1305Big(msg []byte, key *[Poly1305KeySize]byte) []byte {
+	le := func(b []byte) *big.Int {
+		return new(big.Int).SetBytes(reverseBytes(b))
+	}
+	r := le(key[:16])
+	clamp := []byte{
+		0xff, 0xff, 0xff, 0x0f, 0xfc, 0xff, 0xff, 0x0f,
+		0xfc, 0xff, 0xff, 0x0f, 0xfc, 0xff, 0xff, 0x0f,
+	}
+	r.And(r, le(clamp))
+	p := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 130), big.NewInt(5))
+
+	h := new(big.Int)
+	for len(msg) > 0 {
+		n := len(msg)
+		if n > 16 {
+			n = 16
+		}
+		block := append(append([]byte(nil), msg[:n]...), 1)
+		h.Add(h, le(block))
+		h.Mul(h, r)
+		h.Mod(h, p)
+		msg = msg[n:]
+	}
+	h.Add(h, le(key[16:]))
+
+	out := make([]byte, 16)
+	b := h.Bytes()
+	for i := 0; i < len(b) && i < 16; i++ {
+		out[i] = b[len(b)-1-i]
+	}
+	return out
+}
+
+func TestPoly1305Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		var key [Poly1305KeySize]byte
+		rnd.Read(key[:])
+		msg := make([]byte, rnd.Intn(200))
+		rnd.Read(msg)
+		if i%4 == 0 {
+			// Saturated input drives the carries.
+			for j := range msg {
+				msg[j] = 0xff
+			}
+			for j := 0; j < 16; j++ {
+				key[j] = 0xff
+			}
+		}
+
+		var tag [Poly1305TagSize]byte
+		Poly1305Sum(&tag, msg, &key)
+		if expected := poly1305Big(msg, &key); !bytes.Equal(tag[:], expected) {
+			t.Fatalf("%d: Is %x, should: %x", i, tag, expected)
+		}
+
+		// Writes of any size give the same tag.
+		p := newPoly1305(key[:16], key[16:])
+		for rest := msg; len(rest) > 0; {
+			n := rnd.Intn(40)
+			if n > len(rest) {
+				n = len(rest)
+			}
+			p.Write(rest[:n])
+			rest = rest[n:]
+		}
+		var split [Poly1305TagSize]byte
+		p.Sum(&split)
+		if split != tag {
+			t.Fatalf("%d: split writes. Is %x, should: %x", i, split, tag)
+		}
+	}
+}
+
+// Poly1305-AES examples of appendix B of Bernstein's paper.
+func TestPoly1305SerpentWithAES(t *testing.T) {
+	tests := []struct {
+		k, r, nonce, msg, tag string
+	}{
+		{
+			"6acb5f61a7176dd320c5c1eb2edcdc74",
+			"48443d0bb0d21109c89a100b5ce2c208",
+			"ae212a55399729595dea458bc621ff0e",
+			"663cea190ffb83d89593f3f476b6bc24d7e679107ea26adb8caf6652d0656136",
+			"0ee1c16bb73f0f4fd19881753c01cdbe",
+		},
+		{
+			"75deaa25c09f208e1dc4ce6b5cad3fbf",
+			"a0f3080000f46400d0c7e9076c834403",
+			"61ee09218d29b0aaed7e154a2c5509cc",
+			"",
+			"dd3fab2251f11ac759f0887129cc2ee7",
+		},
+	}
+	for i, tt := range tests {
+		p, err := newPoly1305Serpent(aes.NewCipher, mustHex(t, tt.k+tt.r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce := mustHex(t, tt.nonce)
+		msg := mustHex(t, tt.msg)
+		expected := mustHex(t, tt.tag)
+		if tag := p.Sum(nil, nonce, msg); !bytes.Equal(tag, expected) {
+			t.Errorf("%d: Is %x, should: %x", i, tag, expected)
+		}
+		if !p.Verify(expected, nonce, msg) {
+			t.Errorf("%d: valid tag rejected", i)
+		}
+	}
+}
+
+func TestPoly1305Serpent(t *testing.T) {
+	key, iv := testKeyAndIV()
+	r := iv
+	for _, size := range []int{16, 24, 32} {
+		p, err := NewPoly1305Serpent(append(key[:size:size], r...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := testMessage(50)
+		tag := p.Sum(nil, iv, msg)
+
+		// s is the Serpent encryption of the nonce.
+		b, _ := NewCipher(key[:size])
+		var oneTime [Poly1305KeySize]byte
+		copy(oneTime[:], r)
+		b.Encrypt(oneTime[16:], iv)
+		var expected [Poly1305TagSize]byte
+		Poly1305Sum(&expected, msg, &oneTime)
+		if !bytes.Equal(tag, expected[:]) {
+			t.Errorf("%d: Is %x, should: %x", size, tag, expected)
+		}
+
+		other := append([]byte(nil), iv...)
+		other[0] ^= 1
+		if p.Verify(tag, other, msg) {
+			t.Errorf("%d: tag accepted under another nonce", size)
+		}
+	}
+	if _, err := NewPoly1305Serpent(make([]byte, 20)); err == nil {
+		t.Error("short key accepted")
+	}
+}
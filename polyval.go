@@ -0,0 +1,63 @@
+/*
+	polyval.go:  Serpent algorithm implementation in Go.
+
+	POLYVAL (RFC 8452), computed with GHASH:
+	POLYVAL(H, X_1, ..., X_n) = ByteReverse(GHASH(mulX_GHASH(ByteReverse(H)),
+	ByteReverse(X_1), ..., ByteReverse(X_n))).
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+type polyval struct {
+	g ghash
+}
+
+func newPOLYVAL(h []byte) *polyval {
+	var rh [BlockSize]byte
+	reverseBlock(&rh, h)
+	x := loadFieldElement(rh[:])
+	reduce := -(x.lo & 1)
+	x.lo = (x.lo >> 1) | (x.hi << 63)
+	x.hi = (x.hi >> 1) ^ (0xe100000000000000 & reduce)
+	return &polyval{g: ghash{h: x}}
+}
+
+// update hashes data; a last partial block is padded with zeros.
+func (p *polyval) update(data []byte) {
+	for len(data) > 0 {
+		var block, rb [BlockSize]byte
+		n := copy(block[:], data)
+		data = data[n:]
+		reverseBlock(&rb, block[:])
+		p.g.update(rb[:])
+	}
+}
+
+func (p *polyval) sum(out []byte) {
+	var s [BlockSize]byte
+	p.g.sum(s[:])
+	var rs [BlockSize]byte
+	reverseBlock(&rs, s[:])
+	copy(out, rs[:])
+}
+
+func reverseBlock(out *[BlockSize]byte, in []byte) {
+	for i := 0; i < BlockSize; i++ {
+		out[i] = in[BlockSize-1-i]
+	}
+}
@@ -1354,3 +1354,51 @@ func TestDencryptGivenKHat(t *testing.T) {
 		t.Errorf("Bad values. Is %s, should: %s\n", blockStr(plainText), blockStr(expected))
 	}
 }
+
+func TestMakeKeyBytes(t *testing.T) {
+	// Raw key bytes are the hex string of MakeKey read backwards.
+	hexKeys := []string{
+		"abcdef1234567890abcdef1234567890",
+		"1234567890abcdef1234567890abcdef1234567890abcdef",
+		"1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef",
+	}
+	for _, hexKey := range hexKeys {
+		expected := NewKeyInstance()
+		MakeKey(expected, len(hexKey)*4, []byte(hexKey))
+
+		n := len(hexKey) / 2
+		rawKey := make([]byte, n)
+		for i := 0; i < n; i++ {
+			fmt.Sscanf(hexKey[2*i:2*i+2], "%02x", &rawKey[n-1-i])
+		}
+		key, err := NewKey(rawKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.keyLen != expected.keyLen {
+			t.Errorf("Bad key length. Is %d, should: %d\n", key.keyLen, expected.keyLen)
+		}
+		if !slicesAreEqual(key.userKey, expected.userKey) {
+			t.Errorf("Bad user key. Is %x, should: %x\n", key.userKey, expected.userKey)
+		}
+		if !keyScheduleAreEqual(key.kHat(), expected.KHat) {
+			t.Errorf("Bad key schedule for %s\n", hexKey)
+		}
+	}
+
+	// A shorter key must not keep words of a previous longer one.
+	key, _ := NewKey(make([]byte, 32))
+	if err := MakeKeyBytes(key, make([]byte, 16)); err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint{0, 0, 0, 0, 1, 0, 0, 0}
+	if !slicesAreEqual(key.userKey, expected) {
+		t.Errorf("Bad user key. Is %x, should: %x\n", key.userKey, expected)
+	}
+
+	for _, n := range []int{0, 12, 15, 18, 36} {
+		if _, err := NewKey(make([]byte, n)); err != KeySizeError(n) {
+			t.Errorf("Key of %d bytes. Error is %v, should: %v\n", n, err, KeySizeError(n))
+		}
+	}
+}
@@ -0,0 +1,197 @@
+/*
+	siv.go:  Serpent algorithm implementation in Go.
+
+	Synthetic IV mode (RFC 5297): S2V over Serpent-CMAC plus Serpent-CTR.
+	Encryption is deterministic, so equal inputs give equal outputs,
+	and a repeated nonce reveals only that the same message was sent.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+// sivMaxComponents is the largest number of strings S2V takes
+// (RFC 5297, section 2.6), including the plaintext.
+const sivMaxComponents = BITS_PER_BLOCK - 1
+
+// SIV is the deterministic authenticated encryption of RFC 5297.
+// The output is the 16-byte synthetic IV followed by the ciphertext.
+type SIV struct {
+	mac cipher.Block // K1, for S2V
+	ctr cipher.Block // K2, for CTR
+}
+
+// NewSIV returns SIV for a double length key: 32, 48 or 64 bytes,
+// the first half keys S2V and the second half keys CTR.
+func NewSIV(key []byte) (*SIV, error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, KeySizeError(len(key))
+	}
+	mac, _ := NewCipher(key[:len(key)/2])
+	ctr, _ := NewCipher(key[len(key)/2:])
+	return newSIV(mac, ctr)
+}
+
+func newSIV(mac, ctr cipher.Block) (*SIV, error) {
+	if mac.BlockSize() != BlockSize || ctr.BlockSize() != BlockSize {
+		return nil, errors.New("serpent: SIV requires a 128-bit block cipher")
+	}
+	return &SIV{mac: mac, ctr: ctr}, nil
+}
+
+// Overhead is the number of bytes Seal adds to the plaintext.
+func (s *SIV) Overhead() int {
+	return BlockSize
+}
+
+// Seal encrypts and authenticates plaintext together with the
+// additional data components and appends the result to dst.
+// To use a nonce, pass it as the last additional data component.
+func (s *SIV) Seal(dst, plaintext []byte, additionalData ...[]byte) []byte {
+	if len(additionalData) > sivMaxComponents-1 {
+		panic("serpent: too many additional data components for SIV")
+	}
+	ret, out := sliceForAppend(dst, BlockSize+len(plaintext))
+	v := s.s2v(plaintext, additionalData)
+	// With dst = plaintext[:0] the output is the plaintext shifted by one
+	// block; copy handles the overlap, then CTR runs in place.
+	copy(out[BlockSize:], plaintext)
+	s.crypt(out[BlockSize:], out[BlockSize:], &v)
+	copy(out, v[:])
+	return ret
+}
+
+// Open decrypts and authenticates ciphertext with the additional data
+// components given to Seal and appends the plaintext to dst.
+func (s *SIV) Open(dst, ciphertext []byte, additionalData ...[]byte) ([]byte, error) {
+	if len(additionalData) > sivMaxComponents-1 {
+		panic("serpent: too many additional data components for SIV")
+	}
+	if len(ciphertext) < BlockSize {
+		return nil, errOpen
+	}
+	var v [BlockSize]byte
+	copy(v[:], ciphertext)
+	ciphertext = ciphertext[BlockSize:]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	copy(out, ciphertext)
+	s.crypt(out, out, &v)
+	expected := s.s2v(out, additionalData)
+	if subtle.ConstantTimeCompare(expected[:], v[:]) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// s2v computes S2V(K1, AD1, ..., ADn, plaintext).
+func (s *SIV) s2v(plaintext []byte, additionalData [][]byte) [BlockSize]byte {
+	m := newCMAC(s.mac)
+	var d, t [BlockSize]byte
+	m.Write(d[:])
+	copy(d[:], m.Sum(nil))
+
+	for _, ad := range additionalData {
+		double(&d, &d)
+		m.Reset()
+		m.Write(ad)
+		xorBytes(d[:], d[:], m.Sum(nil))
+	}
+
+	m.Reset()
+	if len(plaintext) >= BlockSize {
+		n := len(plaintext) - BlockSize
+		m.Write(plaintext[:n])
+		xorBytes(t[:], plaintext[n:], d[:])
+		m.Write(t[:])
+	} else {
+		double(&t, &d)
+		xorBytes(t[:], t[:], plaintext)
+		t[len(plaintext)] ^= 0x80
+		m.Write(t[:])
+	}
+	var v [BlockSize]byte
+	copy(v[:], m.Sum(nil))
+	return v
+}
+
+// crypt runs CTR from V with bits 31 and 63 cleared.
+func (s *SIV) crypt(out, in []byte, v *[BlockSize]byte) {
+	q := *v
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	stream, _ := NewCTR(s.ctr, q[:])
+	stream.XORKeyStream(out, in)
+}
+
+type sivAEAD struct {
+	siv       *SIV
+	nonceSize int
+}
+
+// NewSIVAEAD returns SIV as a cipher.AEAD. The nonce is the last
+// header component of S2V (RFC 5297, section 3). With nonceSize 0
+// no nonce is used and the encryption is fully deterministic.
+func NewSIVAEAD(key []byte, nonceSize int) (cipher.AEAD, error) {
+	if nonceSize < 0 {
+		return nil, errors.New("serpent: invalid SIV nonce size")
+	}
+	siv, err := NewSIV(key)
+	if err != nil {
+		return nil, err
+	}
+	return &sivAEAD{siv: siv, nonceSize: nonceSize}, nil
+}
+
+func (a *sivAEAD) NonceSize() int {
+	return a.nonceSize
+}
+
+func (a *sivAEAD) Overhead() int {
+	return BlockSize
+}
+
+func (a *sivAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != a.nonceSize {
+		panic("serpent: incorrect nonce length given to SIV")
+	}
+	return a.siv.Seal(dst, plaintext, a.components(nonce, additionalData)...)
+}
+
+func (a *sivAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != a.nonceSize {
+		panic("serpent: incorrect nonce length given to SIV")
+	}
+	return a.siv.Open(dst, ciphertext, a.components(nonce, additionalData)...)
+}
+
+func (a *sivAEAD) components(nonce, additionalData []byte) [][]byte {
+	if a.nonceSize == 0 {
+		return [][]byte{additionalData}
+	}
+	return [][]byte{additionalData, nonce}
+}
@@ -0,0 +1,185 @@
+/*
+	siv_test.go:  Unit tests of the SIV mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+// Test vectors of RFC 5297 appendix A (AES-SIV).
+func TestSIVWithAES(t *testing.T) {
+	tests := []struct {
+		key            string
+		additionalData []string
+		plainText      string
+		cipherText     string
+	}{
+		{
+			"fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+			[]string{"101112131415161718191a1b1c1d1e1f2021222324252627"},
+			"112233445566778899aabbccddee",
+			"85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c",
+		},
+		{
+			"7f7e7d7c7b7a79787776757473727170404142434445464748494a4b4c4d4e4f",
+			[]string{
+				"00112233445566778899aabbccddeeffdeaddadadeaddadaffeeddccbbaa99887766554433221100",
+				"102030405060708090a0",
+				"09f911029d74e35bd84156c5635688c0",
+			},
+			"7468697320697320736f6d6520706c61696e7465787420746f20656e6372797074207573696e67205349562d414553",
+			"7bdb6e3b432667eb06f4d14bff2fbd0fcb900f2fddbe404326601965c889bf17" +
+				"dba77ceb094fa663b7a3f748ba8af829ea64ad544a272e9c485b62a3fd5c0d",
+		},
+	}
+	for i, tt := range tests {
+		key := mustHex(t, tt.key)
+		mac, _ := aes.NewCipher(key[:16])
+		ctr, _ := aes.NewCipher(key[16:])
+		siv, err := newSIV(mac, ctr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var additionalData [][]byte
+		for _, ad := range tt.additionalData {
+			additionalData = append(additionalData, mustHex(t, ad))
+		}
+		plainText, expected := mustHex(t, tt.plainText), mustHex(t, tt.cipherText)
+
+		output := siv.Seal(nil, plainText, additionalData...)
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d: Seal. Is %x, should: %x", i, output, expected)
+		}
+		decrypted, err := siv.Open(nil, expected, additionalData...)
+		if err != nil || !bytes.Equal(decrypted, plainText) {
+			t.Errorf("%d: Open. Is (%x, %v), should: %x", i, decrypted, err, plainText)
+		}
+		if _, err := siv.Open(nil, expected, additionalData[1:]...); err != errOpen {
+			t.Errorf("%d: Missing component. Error is %v, should: %v", i, err, errOpen)
+		}
+	}
+}
+
+// Fixtures generated with libgcrypt (GCRY_CIPHER_MODE_SIV); every
+// component is a separate gcry_cipher_authenticate call or the IV.
+func TestSIVVectors(t *testing.T) {
+	for i, v := range readVectors(t, "siv.txt") {
+		key, plainText, expected, additionalData := v[0], v[1], v[2], v[3:]
+		siv, err := NewSIV(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output := siv.Seal(nil, plainText, additionalData...)
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d: Seal. Is %x, should: %x", i, output, expected)
+		}
+		decrypted, err := siv.Open(nil, expected, additionalData...)
+		if err != nil || !bytes.Equal(decrypted, plainText) {
+			t.Errorf("%d: Open. Is (%x, %v), should: %x", i, decrypted, err, plainText)
+		}
+	}
+}
+
+func TestSIVInPlace(t *testing.T) {
+	rawKey, iv := testKeyAndIV()
+	aead, err := NewSIVAEAD(rawKey, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := iv[:aead.NonceSize()]
+	for _, n := range []int{0, 5, 16, 17, 37, 200} {
+		plainText := testMessage(n)
+		expected := aead.Seal(nil, nonce, plainText, []byte("header"))
+
+		buf := make([]byte, n, n+aead.Overhead())
+		copy(buf, plainText)
+		sealed := aead.Seal(buf[:0], nonce, buf, []byte("header"))
+		if !bytes.Equal(sealed, expected) {
+			t.Errorf("%d bytes. Seal. Is %x, should: %x", n, sealed, expected)
+		}
+		opened, err := aead.Open(sealed[:0], nonce, sealed, []byte("header"))
+		if err != nil || !bytes.Equal(opened, plainText) {
+			t.Errorf("%d bytes. Open. Is (%x, %v), should: %x", n, opened, err, plainText)
+		}
+	}
+}
+
+func TestSIV(t *testing.T) {
+	for _, keySize := range []int{32, 48, 64} {
+		siv, err := NewSIV(testMessage(keySize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range []int{0, 1, 15, 16, 17, 100} {
+			plainText := testMessage(n)
+			sealed := siv.Seal(nil, plainText, []byte("table"), []byte("column"))
+			if len(sealed) != n+siv.Overhead() {
+				t.Fatalf("Sealed length. Is %d, should: %d", len(sealed), n+siv.Overhead())
+			}
+			again := siv.Seal(nil, plainText, []byte("table"), []byte("column"))
+			if !bytes.Equal(sealed, again) {
+				t.Errorf("Key %d, %d bytes. Seal is not deterministic", keySize, n)
+			}
+			opened, err := siv.Open(nil, sealed, []byte("table"), []byte("column"))
+			if err != nil || !bytes.Equal(opened, plainText) {
+				t.Errorf("Key %d, %d bytes. Is (%x, %v), should: %x", keySize, n, opened, err, plainText)
+			}
+			// components are not concatenated
+			if _, err := siv.Open(nil, sealed, []byte("tablecolumn")); err != errOpen {
+				t.Errorf("Key %d, %d bytes. Joined components. Error is %v, should: %v", keySize, n, err, errOpen)
+			}
+		}
+	}
+	if _, err := NewSIV(make([]byte, 32-1)); err != KeySizeError(31) {
+		t.Errorf("Error is %v, should: %v", err, KeySizeError(31))
+	}
+}
+
+func TestSIVAEAD(t *testing.T) {
+	key := testMessage(64)
+	siv, _ := NewSIV(key)
+	nonce := []byte("nonce-0123456789")
+
+	aead, err := NewSIVAEAD(key, len(nonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainText := testMessage(40)
+	sealed := aead.Seal(nil, nonce, plainText, []byte("header"))
+	expected := siv.Seal(nil, plainText, []byte("header"), nonce)
+	if !bytes.Equal(sealed, expected) {
+		t.Errorf("Is %x, should: %x", sealed, expected)
+	}
+	opened, err := aead.Open(nil, nonce, sealed, []byte("header"))
+	if err != nil || !bytes.Equal(opened, plainText) {
+		t.Errorf("Is (%x, %v), should: %x", opened, err, plainText)
+	}
+
+	// without nonce
+	aead, _ = NewSIVAEAD(key, 0)
+	sealed = aead.Seal(nil, nil, plainText, nil)
+	expected = siv.Seal(nil, plainText, nil)
+	if !bytes.Equal(sealed, expected) {
+		t.Errorf("No nonce. Is %x, should: %x", sealed, expected)
+	}
+}
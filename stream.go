@@ -0,0 +1,250 @@
+/*
+	stream.go:  Serpent algorithm implementation in Go.
+
+	Chunked authenticated encryption of long streams, the STREAM
+	construction of Hoang, Reyhanitabar, Rogaway and Vizár.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	// DefaultSegmentSize is the plaintext size of a stream segment
+	// used when zero is given.
+	DefaultSegmentSize = 64 << 10
+
+	// streamNonceSuffix is the 32-bit segment counter and the
+	// last segment flag that follow the nonce prefix.
+	streamNonceSuffix = 5
+)
+
+var (
+	// ErrStreamAuth is returned when a segment fails authentication,
+	// including a stream that was truncated, reordered or extended.
+	ErrStreamAuth = errors.New("serpent: stream segment authentication failed")
+	// ErrStreamTooLong is returned when the segment counter would wrap.
+	ErrStreamTooLong = errors.New("serpent: stream too long")
+	// ErrStreamClosed is returned by Write after Close.
+	ErrStreamClosed = errors.New("serpent: write to closed stream")
+)
+
+// streamSegments holds what StreamWriter and StreamReader share: each
+// segment is sealed under the nonce prefix || counter || last flag,
+// with the counter as a 32-bit big-endian number and the flag 1 only
+// for the final segment.
+type streamSegments struct {
+	aead        cipher.AEAD
+	nonce       []byte
+	segmentSize int
+	counter     uint32
+}
+
+func newStreamSegments(aead cipher.AEAD, noncePrefix []byte, segmentSize int) (streamSegments, error) {
+	if segmentSize == 0 {
+		segmentSize = DefaultSegmentSize
+	}
+	if segmentSize < 0 || segmentSize > math.MaxInt32-aead.Overhead()-1 {
+		return streamSegments{}, errors.New("serpent: invalid stream segment size")
+	}
+	if len(noncePrefix)+streamNonceSuffix != aead.NonceSize() {
+		return streamSegments{}, errors.New("serpent: stream nonce prefix must be 5 bytes shorter than the AEAD nonce")
+	}
+	s := streamSegments{aead: aead, nonce: make([]byte, aead.NonceSize()), segmentSize: segmentSize}
+	copy(s.nonce, noncePrefix)
+	return s, nil
+}
+
+// next sets the nonce for the current segment and advances the counter.
+func (s *streamSegments) next(last bool) ([]byte, error) {
+	if !last && s.counter == math.MaxUint32 {
+		return nil, ErrStreamTooLong
+	}
+	suffix := s.nonce[len(s.nonce)-streamNonceSuffix:]
+	binary.BigEndian.PutUint32(suffix, s.counter)
+	suffix[4] = 0
+	if last {
+		suffix[4] = 1
+	}
+	s.counter++
+	return s.nonce, nil
+}
+
+// StreamWriter encrypts everything written to it in segments of
+// segmentSize plaintext bytes. Close must be called to write the
+// final segment; without it the stream does not decrypt.
+type StreamWriter struct {
+	w      io.Writer
+	s      streamSegments
+	buf    []byte // plaintext of the current segment
+	out    []byte // sealed segment
+	err    error
+	closed bool
+}
+
+// NewStreamWriter returns a writer that seals segments with aead, for
+// example NewGCM over NewCipher, and writes them to w. The nonce prefix
+// must be aead.NonceSize()-5 bytes and must never repeat under one key;
+// use a random prefix or a fresh key per stream. A segmentSize of zero
+// means DefaultSegmentSize.
+func NewStreamWriter(w io.Writer, aead cipher.AEAD, noncePrefix []byte, segmentSize int) (*StreamWriter, error) {
+	s, err := newStreamSegments(aead, noncePrefix, segmentSize)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamWriter{
+		w:   w,
+		s:   s,
+		buf: make([]byte, 0, s.segmentSize),
+		out: make([]byte, 0, s.segmentSize+aead.Overhead()),
+	}, nil
+}
+
+// Write encrypts p. A segment is written out only when the next one
+// has started, since only Close knows which segment is the last.
+func (sw *StreamWriter) Write(p []byte) (int, error) {
+	if sw.closed {
+		return 0, ErrStreamClosed
+	}
+	written := 0
+	for len(p) > 0 {
+		if sw.err != nil {
+			return written, sw.err
+		}
+		if len(sw.buf) == sw.s.segmentSize {
+			sw.seal(false)
+			continue
+		}
+		k := copy(sw.buf[len(sw.buf):sw.s.segmentSize], p)
+		sw.buf = sw.buf[:len(sw.buf)+k]
+		p = p[k:]
+		written += k
+	}
+	return written, sw.err
+}
+
+// Close writes the final segment. It does not close the underlying writer.
+func (sw *StreamWriter) Close() error {
+	if sw.closed {
+		return sw.err
+	}
+	sw.closed = true
+	if sw.err == nil {
+		sw.seal(true)
+	}
+	return sw.err
+}
+
+func (sw *StreamWriter) seal(last bool) {
+	nonce, err := sw.s.next(last)
+	if err != nil {
+		sw.err = err
+		return
+	}
+	sw.out = sw.s.aead.Seal(sw.out[:0], nonce, sw.buf, nil)
+	sw.buf = sw.buf[:0]
+	if _, err := sw.w.Write(sw.out); err != nil {
+		sw.err = err
+	}
+}
+
+// StreamReader decrypts a stream written by StreamWriter. Read only
+// returns plaintext of segments that passed authentication; a stream
+// that is cut short or has data after the final segment gives
+// ErrStreamAuth instead of io.EOF.
+type StreamReader struct {
+	r       io.Reader
+	s       streamSegments
+	buf     []byte // sealed segment and one byte of the next one
+	pending int    // bytes of the next segment already in buf
+	opened  []byte // plaintext buffer
+	plain   []byte // authenticated plaintext not yet returned
+	done    bool
+	err     error
+}
+
+// NewStreamReader returns a reader that decrypts the segments read
+// from r, with the same aead, nonce prefix and segment size as the
+// StreamWriter.
+func NewStreamReader(r io.Reader, aead cipher.AEAD, noncePrefix []byte, segmentSize int) (*StreamReader, error) {
+	s, err := newStreamSegments(aead, noncePrefix, segmentSize)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamReader{
+		r:      r,
+		s:      s,
+		buf:    make([]byte, s.segmentSize+aead.Overhead()+1),
+		opened: make([]byte, 0, s.segmentSize),
+	}, nil
+}
+
+func (sr *StreamReader) Read(p []byte) (int, error) {
+	for len(sr.plain) == 0 {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+		if sr.done {
+			return 0, io.EOF
+		}
+		sr.err = sr.open()
+	}
+	n := copy(p, sr.plain)
+	sr.plain = sr.plain[n:]
+	return n, nil
+}
+
+// open reads and authenticates the next segment. A segment is the last
+// one when it is followed by the end of the stream, so one byte more
+// than a full segment is read.
+func (sr *StreamReader) open() error {
+	n, err := io.ReadFull(sr.r, sr.buf[sr.pending:])
+	n += sr.pending
+	last := false
+	switch err {
+	case nil:
+		n--
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+
+	nonce, err := sr.s.next(last)
+	if err != nil {
+		return err
+	}
+	plain, err := sr.s.aead.Open(sr.opened[:0], nonce, sr.buf[:n], nil)
+	if err != nil {
+		return ErrStreamAuth
+	}
+	sr.plain = plain
+	if last {
+		sr.done = true
+	} else {
+		sr.buf[0] = sr.buf[n]
+		sr.pending = 1
+	}
+	return nil
+}
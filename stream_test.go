@@ -0,0 +1,196 @@
+/*
+	stream_test.go:  Unit tests of the STREAM segmented encryption.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+const testSegmentSize = 64
+
+func testStreamAEAD(t *testing.T) (cipher.AEAD, []byte) {
+	rawKey, iv := testKeyAndIV()
+	block, _ := NewCipher(rawKey)
+	aead, err := NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aead, iv[:aead.NonceSize()-5]
+}
+
+func sealStream(t *testing.T, plainText []byte) []byte {
+	aead, prefix := testStreamAEAD(t)
+	var out bytes.Buffer
+	w, err := NewStreamWriter(&out, aead, prefix, testSegmentSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plainText); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func openStream(t *testing.T, sealed []byte) ([]byte, error) {
+	aead, prefix := testStreamAEAD(t)
+	r, err := NewStreamReader(bytes.NewReader(sealed), aead, prefix, testSegmentSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return io.ReadAll(r)
+}
+
+func TestStream(t *testing.T) {
+	aead, prefix := testStreamAEAD(t)
+	segment := testSegmentSize + aead.Overhead()
+
+	for _, n := range []int{0, 1, 63, 64, 65, 128, 1000} {
+		plainText := testMessage(n)
+		sealed := sealStream(t, plainText)
+
+		segments := n/testSegmentSize + 1
+		if n > 0 && n%testSegmentSize == 0 {
+			segments--
+		}
+		if len(sealed) != n+segments*aead.Overhead() {
+			t.Errorf("%d bytes. Sealed length is %d, should: %d", n, len(sealed), n+segments*aead.Overhead())
+		}
+
+		// Segment i is sealed on its own under prefix || i || last.
+		for i := 0; i < segments; i++ {
+			nonce := append(append([]byte(nil), prefix...), 0, 0, 0, byte(i), 0)
+			if i == segments-1 {
+				nonce[len(nonce)-1] = 1
+			}
+			end := (i + 1) * segment
+			if end > len(sealed) {
+				end = len(sealed)
+			}
+			if _, err := aead.Open(nil, nonce, sealed[i*segment:end], nil); err != nil {
+				t.Errorf("%d bytes. Segment %d: %v", n, i, err)
+			}
+		}
+
+		opened, err := openStream(t, sealed)
+		if err != nil || !bytes.Equal(opened, plainText) {
+			t.Errorf("%d bytes. Is (%x, %v), should: %x", n, opened, err, plainText)
+		}
+
+		// Small writes and reads give the same result.
+		var out bytes.Buffer
+		w, _ := NewStreamWriter(&out, aead, prefix, testSegmentSize)
+		for p := plainText; len(p) > 0; p = p[1:] {
+			w.Write(p[:1])
+		}
+		w.Close()
+		if !bytes.Equal(out.Bytes(), sealed) {
+			t.Errorf("%d bytes. One byte writes differ", n)
+		}
+		r, _ := NewStreamReader(iotest.OneByteReader(bytes.NewReader(sealed)), aead, prefix, testSegmentSize)
+		opened, err = io.ReadAll(iotest.OneByteReader(r))
+		if err != nil || !bytes.Equal(opened, plainText) {
+			t.Errorf("%d bytes. One byte reads. Is (%x, %v), should: %x", n, opened, err, plainText)
+		}
+	}
+}
+
+func TestStreamTampering(t *testing.T) {
+	aead, _ := testStreamAEAD(t)
+	segment := testSegmentSize + aead.Overhead()
+	plainText := testMessage(4*testSegmentSize + 10)
+	sealed := sealStream(t, plainText)
+	extra := sealStream(t, testMessage(10))
+
+	tests := []struct {
+		name   string
+		sealed []byte
+	}{
+		{"empty", nil},
+		{"truncated at a segment", sealed[:2*segment]},
+		{"truncated in a segment", sealed[:2*segment+20]},
+		{"last segment removed", sealed[:4*segment]},
+		{"byte appended", append(append([]byte(nil), sealed...), 0)},
+		{"stream appended", append(append([]byte(nil), sealed...), extra...)},
+		{"segments swapped", append(append(append([]byte(nil), sealed[segment:2*segment]...),
+			sealed[:segment]...), sealed[2*segment:]...)},
+		{"segment repeated", append(append([]byte(nil), sealed[:segment]...), sealed...)},
+		{"bit flipped", func() []byte {
+			b := append([]byte(nil), sealed...)
+			b[3*segment+5] ^= 1
+			return b
+		}()},
+	}
+	for _, tt := range tests {
+		if _, err := openStream(t, tt.sealed); err != ErrStreamAuth {
+			t.Errorf("%s. Error is %v, should: %v", tt.name, err, ErrStreamAuth)
+		}
+	}
+}
+
+// Plaintext of a segment that fails authentication is never returned.
+func TestStreamNoUnauthenticatedPlaintext(t *testing.T) {
+	aead, prefix := testStreamAEAD(t)
+	segment := testSegmentSize + aead.Overhead()
+	plainText := testMessage(3 * testSegmentSize)
+	sealed := sealStream(t, plainText)
+	sealed[2*segment-1] ^= 1 // tag of the second segment
+
+	r, _ := NewStreamReader(bytes.NewReader(sealed), aead, prefix, testSegmentSize)
+	opened, err := io.ReadAll(r)
+	if err != ErrStreamAuth {
+		t.Errorf("Error is %v, should: %v", err, ErrStreamAuth)
+	}
+	if !bytes.Equal(opened, plainText[:testSegmentSize]) {
+		t.Errorf("Released %d bytes, should: %d", len(opened), testSegmentSize)
+	}
+	if n, err := r.Read(make([]byte, 10)); n != 0 || err != ErrStreamAuth {
+		t.Errorf("Read after failure. Is (%d, %v), should: (0, %v)", n, err, ErrStreamAuth)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	aead, prefix := testStreamAEAD(t)
+	if _, err := NewStreamWriter(io.Discard, aead, prefix[:3], 0); err == nil {
+		t.Error("short nonce prefix accepted")
+	}
+	if _, err := NewStreamReader(bytes.NewReader(nil), aead, prefix, -1); err == nil {
+		t.Error("negative segment size accepted")
+	}
+
+	w, _ := NewStreamWriter(io.Discard, aead, prefix, 0)
+	w.Close()
+	if _, err := w.Write([]byte{1}); err != ErrStreamClosed {
+		t.Errorf("Write after Close. Error is %v, should: %v", err, ErrStreamClosed)
+	}
+
+	// Errors of the underlying reader are passed on.
+	r, _ := NewStreamReader(iotest.ErrReader(iotest.ErrTimeout), aead, prefix, testSegmentSize)
+	if _, err := io.Copy(io.Discard, r); err != iotest.ErrTimeout {
+		t.Errorf("Reader error is %v, should: %v", err, iotest.ErrTimeout)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+	xts.go:  Serpent algorithm implementation in Go.
+
+	XTS tweakable mode for sector-level encryption (IEEE 1619),
+	with ciphertext stealing for data units that are not a multiple
+	of the block size.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+package serpent
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const xtsBatch = 8
+
+var (
+	// ErrXTSKey is returned for a key whose two halves are equal.
+	ErrXTSKey = errors.New("serpent: XTS key halves must differ")
+	// ErrXTSLength is returned for a data unit shorter than one block.
+	ErrXTSLength = errors.New("serpent: XTS data unit shorter than one block")
+)
+
+// XTS encrypts data units (sectors) of at least 16 bytes.
+// It keeps no state between calls, so different sectors may be
+// processed concurrently.
+type XTS struct {
+	k1, k2 cipher.Block // data key, tweak key
+}
+
+// NewXTS returns XTS for a double length key of 32, 48 or 64 bytes:
+// the first half is the data key, the second half the tweak key.
+func NewXTS(key []byte) (*XTS, error) {
+	return newXTS(NewCipher, key)
+}
+
+func newXTS(newCipher func([]byte) (cipher.Block, error), key []byte) (*XTS, error) {
+	if len(key)%2 != 0 {
+		return nil, KeySizeError(len(key))
+	}
+	half := len(key) / 2
+	if subtle.ConstantTimeCompare(key[:half], key[half:]) == 1 {
+		return nil, ErrXTSKey
+	}
+	k1, err := newCipher(key[:half])
+	if err != nil {
+		return nil, KeySizeError(len(key))
+	}
+	k2, err := newCipher(key[half:])
+	if err != nil {
+		return nil, KeySizeError(len(key))
+	}
+	if k1.BlockSize() != BlockSize {
+		return nil, errors.New("serpent: XTS requires a 128-bit block cipher")
+	}
+	return &XTS{k1: k1, k2: k2}, nil
+}
+
+// SectorTweak returns the tweak of a sector number: the number as
+// a little-endian 128-bit value (plain64 in dm-crypt).
+func SectorTweak(sector uint64) [BlockSize]byte {
+	var tweak [BlockSize]byte
+	binary.LittleEndian.PutUint64(tweak[:], sector)
+	return tweak
+}
+
+// Encrypt encrypts the data unit src with the tweak into dst.
+// dst and src may be the same slice.
+func (x *XTS) Encrypt(dst, src []byte, tweak [BlockSize]byte) error {
+	return x.crypt(dst, src, tweak, false)
+}
+
+// Decrypt decrypts the data unit src with the tweak into dst.
+func (x *XTS) Decrypt(dst, src []byte, tweak [BlockSize]byte) error {
+	return x.crypt(dst, src, tweak, true)
+}
+
+// EncryptSector encrypts a sector with the tweak SectorTweak(sector).
+func (x *XTS) EncryptSector(dst, src []byte, sector uint64) error {
+	return x.Encrypt(dst, src, SectorTweak(sector))
+}
+
+// DecryptSector decrypts a sector with the tweak SectorTweak(sector).
+func (x *XTS) DecryptSector(dst, src []byte, sector uint64) error {
+	return x.Decrypt(dst, src, SectorTweak(sector))
+}
+
+func (x *XTS) crypt(dst, src []byte, tweak [BlockSize]byte, decrypt bool) error {
+	if len(src) < BlockSize {
+		return ErrXTSLength
+	}
+	if len(dst) < len(src) {
+		panic("serpent: output smaller than input")
+	}
+	var t [BlockSize]byte
+	x.k2.Encrypt(t[:], tweak[:])
+
+	tail := len(src) % BlockSize
+	full := len(src) - tail
+	if tail > 0 {
+		// the last full block takes part in ciphertext stealing
+		full -= BlockSize
+	}
+	x.blocks(dst[:full], src[:full], &t, decrypt)
+	if tail == 0 {
+		return nil
+	}
+
+	var cc, pp [BlockSize]byte
+	last := src[full : full+BlockSize]
+	if !decrypt {
+		x.block(cc[:], last, &t, false)
+		mulAlpha(&t)
+		copy(pp[:], src[full+BlockSize:])
+		copy(pp[tail:], cc[tail:])
+		copy(dst[full+BlockSize:], cc[:tail])
+		x.block(dst[full:], pp[:], &t, false)
+		return nil
+	}
+	next := t
+	mulAlpha(&next)
+	x.block(pp[:], last, &next, true)
+	copy(cc[:], src[full+BlockSize:])
+	copy(cc[tail:], pp[tail:])
+	copy(dst[full+BlockSize:], pp[:tail])
+	x.block(dst[full:], cc[:], &t, true)
+	return nil
+}
+
+// blocks processes whole blocks, advancing the tweak t.
+func (x *XTS) blocks(dst, src []byte, t *[BlockSize]byte, decrypt bool) {
+	var tweaks, buf [xtsBatch * BlockSize]byte
+	for len(src) > 0 {
+		size := len(src)
+		if size > len(buf) {
+			size = len(buf)
+		}
+		for i := 0; i < size; i += BlockSize {
+			copy(tweaks[i:], t[:])
+			mulAlpha(t)
+		}
+		xorBytes(buf[:size], src[:size], tweaks[:size])
+		if decrypt {
+			decryptBlocksWith(x.k1, buf[:size], buf[:size])
+		} else {
+			encryptBlocksWith(x.k1, buf[:size], buf[:size])
+		}
+		xorBytes(dst[:size], buf[:size], tweaks[:size])
+		dst, src = dst[size:], src[size:]
+	}
+}
+
+// block processes one block with the tweak t.
+func (x *XTS) block(dst, src []byte, t *[BlockSize]byte, decrypt bool) {
+	var buf [BlockSize]byte
+	xorBytes(buf[:], src, t[:])
+	if decrypt {
+		x.k1.Decrypt(buf[:], buf[:])
+	} else {
+		x.k1.Encrypt(buf[:], buf[:])
+	}
+	xorBytes(dst, buf[:], t[:])
+}
+
+// mulAlpha multiplies the tweak by the primitive element α of GF(2^128)
+// in the little-endian convention of IEEE 1619.
+func mulAlpha(t *[BlockSize]byte) {
+	carry := t[BlockSize-1] >> 7
+	for i := BlockSize - 1; i > 0; i-- {
+		t[i] = (t[i] << 1) | (t[i-1] >> 7)
+	}
+	t[0] = (t[0] << 1) ^ (0x87 & -carry)
+}
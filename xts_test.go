@@ -0,0 +1,176 @@
+/*
+	xts_test.go:  Unit tests of the XTS mode.
+
+	Copyright (C) 2018 by Piotr Pszczółkowski (piotr@beesoft.pl)
+
+	This library is free software; you can redistribute it and/or
+	modify it under the terms of the GNU Lesser General Public
+	License as published by the Free Software Foundation; either
+	version 2.1 of the License, or (at your option) any later version.
+	This library is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+	Lesser General Public License for more details.
+	You should have received a copy of the GNU Lesser General Public
+	License along with this library; if not, write to the Free Software
+	Foundation, Inc., 59 Temple Place, Suite 330, Boston, MA  02111-1307  USA
+
+	If you require this code under a license other than LGPL, please ask.
+*/
+
+package serpent
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"testing"
+)
+
+// Vectors 2, 3, 15, 16 and 17 of IEEE 1619 (XTS-AES-128).
+func TestXTSWithAES(t *testing.T) {
+	tests := []struct {
+		key        string
+		sector     uint64
+		plainText  string
+		cipherText string
+	}{
+		{
+			"11111111111111111111111111111111" + "22222222222222222222222222222222", 0x3333333333,
+			"4444444444444444444444444444444444444444444444444444444444444444",
+			"c454185e6a16936e39334038acef838bfb186fff7480adc4289382ecd6d394f0",
+		},
+		{
+			"fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0" + "22222222222222222222222222222222", 0x3333333333,
+			"4444444444444444444444444444444444444444444444444444444444444444",
+			"af85336b597afc1a900b2eb21ec949d292df4c047e0b21532186a5971a227a89",
+		},
+		{
+			"fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0" + "bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0", 0x123456789a,
+			"000102030405060708090a0b0c0d0e0f10",
+			"6c1625db4671522d3d7599601de7ca09ed",
+		},
+		{
+			"fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0" + "bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0", 0x123456789a,
+			"000102030405060708090a0b0c0d0e0f1011",
+			"d069444b7a7e0cab09e24447d24deb1fedbf",
+		},
+		{
+			"fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0" + "bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0", 0x123456789a,
+			"000102030405060708090a0b0c0d0e0f101112",
+			"e5df1351c0544ba1350b3363cd8ef4beedbf9d",
+		},
+	}
+	for i, tt := range tests {
+		x, err := newXTS(newAES, mustHex(t, tt.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		plainText, expected := mustHex(t, tt.plainText), mustHex(t, tt.cipherText)
+		output := make([]byte, len(plainText))
+		if err := x.EncryptSector(output, plainText, tt.sector); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d: Encrypt. Is %x, should: %x", i, output, expected)
+		}
+		x.DecryptSector(output, output, tt.sector)
+		if !bytes.Equal(output, plainText) {
+			t.Errorf("%d: Decrypt. Is %x, should: %x", i, output, plainText)
+		}
+	}
+}
+
+// Fixtures generated with libgcrypt (GCRY_CIPHER_MODE_XTS), including
+// lengths that need ciphertext stealing. The tweaks are sector numbers
+// in the plain64 layout, so SectorTweak must reproduce them.
+func TestXTSVectors(t *testing.T) {
+	for i, v := range readVectors(t, "xts.txt") {
+		key, tweak, plainText, expected := v[0], v[1], v[2], v[3]
+		x, err := NewXTS(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sector := binary.LittleEndian.Uint64(tweak)
+		if st := SectorTweak(sector); !bytes.Equal(st[:], tweak) {
+			t.Errorf("%d: SectorTweak. Is %x, should: %x", i, st, tweak)
+		}
+		output := make([]byte, len(plainText))
+		if err := x.EncryptSector(output, plainText, sector); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(output, expected) {
+			t.Errorf("%d: Encrypt. Is %x, should: %x", i, output, expected)
+		}
+		x.DecryptSector(output, output, sector)
+		if !bytes.Equal(output, plainText) {
+			t.Errorf("%d: Decrypt. Is %x, should: %x", i, output, plainText)
+		}
+	}
+}
+
+func TestXTS(t *testing.T) {
+	for _, keySize := range []int{32, 48, 64} {
+		x, err := NewXTS(testMessage(keySize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range []int{16, 17, 31, 32, 33, 200, 512, 4096 + 5} {
+			plainText := testMessage(n)
+			output := make([]byte, n)
+			if err := x.EncryptSector(output, plainText, 7); err != nil {
+				t.Fatal(err)
+			}
+			other := make([]byte, n)
+			x.EncryptSector(other, plainText, 8)
+			if bytes.Equal(output, other) {
+				t.Errorf("Key %d, %d bytes. Sectors 7 and 8 encrypt the same", keySize, n)
+			}
+			x.DecryptSector(output, output, 7)
+			if !bytes.Equal(output, plainText) {
+				t.Errorf("Key %d, %d bytes. Is %x, should: %x", keySize, n, output, plainText)
+			}
+		}
+	}
+}
+
+func TestXTSErrors(t *testing.T) {
+	key := testMessage(32)
+	copy(key[16:], key[:16])
+	if _, err := NewXTS(key); err != ErrXTSKey {
+		t.Errorf("Equal halves. Error is %v, should: %v", err, ErrXTSKey)
+	}
+	if _, err := NewXTS(testMessage(40)); err != KeySizeError(40) {
+		t.Errorf("Error is %v, should: %v", err, KeySizeError(40))
+	}
+	x, _ := NewXTS(testMessage(64))
+	buf := make([]byte, 15)
+	if err := x.EncryptSector(buf, buf, 0); err != ErrXTSLength {
+		t.Errorf("Short data unit. Error is %v, should: %v", err, ErrXTSLength)
+	}
+}
+
+// Sectors may be processed concurrently with one XTS value.
+func TestXTSConcurrent(t *testing.T) {
+	x, _ := NewXTS(testMessage(64))
+	plainText := testMessage(512)
+	expected := make([][]byte, 16)
+	for i := range expected {
+		expected[i] = make([]byte, len(plainText))
+		x.EncryptSector(expected[i], plainText, uint64(i))
+	}
+
+	var wg sync.WaitGroup
+	for i := range expected {
+		wg.Add(1)
+		go func(sector int) {
+			defer wg.Done()
+			output := make([]byte, len(plainText))
+			x.EncryptSector(output, plainText, uint64(sector))
+			if !bytes.Equal(output, expected[sector]) {
+				t.Errorf("Sector %d differs", sector)
+			}
+		}(i)
+	}
+	wg.Wait()
+}